@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+const (
+	bulkMaxBytes   = 10 * 1_048_576
+	bulkMaxRecords = 10_000
+	bulkBatchSize  = 500
+)
+
+// bulkResult reports the outcome of one record of a bulk import, by its position in
+// the request body.
+type bulkResult struct {
+	Index  int               `json:"index"`
+	Status string            `json:"status"` // created, updated, invalid or failed
+	ID     int64             `json:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// bulkCreateMovies imports many movies in one request. In the default atomic mode
+// nothing is saved unless every record is valid; with mode=best_effort the valid
+// records are saved and the rest are reported back. A record sharing an external ID
+// with an existing movie overwrites that movie's title, year, runtime and genres
+// instead of creating a duplicate, so that re-importing a catalogue is safe. Movies in
+// the trash are left alone, as are other users' movies when importing with only
+// movies:write:own, and a movie edited while the import runs fails the import, or its
+// batch in best-effort mode, as an edit conflict. New movies go through the same
+// duplicate check as single creates, and force=true skips it.
+func (app *application) bulkCreateMovies(writer http.ResponseWriter, request *http.Request) {
+	val := validator.New()
+	query := request.URL.Query()
+
+	mode := app.readString(query, "mode", "atomic")
+	force := app.readBool(query, "force", false, val)
+
+	if val.Check(validator.PermittedValue(mode, "atomic", "best_effort"), "mode", "must be atomic or best_effort"); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	records, err := app.readJSONRecords(writer, request, bulkMaxBytes, bulkMaxRecords)
+	if err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	genres, err := app.models.Genres.Vocabulary()
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	results := make([]*bulkResult, len(records))
+	movies := []*data.Movie{}
+	pending := []*bulkResult{}
+
+	for i, record := range records {
+		results[i] = &bulkResult{Index: i}
+
+		var input struct {
+			Title       string           `json:"title"`
+			Year        int32            `json:"year"`
+			Runtime     data.Runtime     `json:"runtime"`
+			Genres      []string         `json:"genres"`
+			ExternalIDs data.ExternalIDs `json:"external_ids"`
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(record))
+		dec.DisallowUnknownFields()
+
+		if err := dec.Decode(&input); err != nil {
+			results[i].Status = "invalid"
+			results[i].Errors = map[string]string{"record": app.jsonError(err).Error()}
+			continue
+		}
+
+		movie := &data.Movie{
+			Title:       input.Title,
+			Year:        input.Year,
+			Runtime:     input.Runtime,
+			Genres:      input.Genres,
+			ExternalIDs: input.ExternalIDs,
+		}
+
+		val := validator.New()
+		if data.ValidateMovie(val, movie, genres); !val.Valid() {
+			results[i].Status = "invalid"
+			results[i].Errors = val.Errors
+			continue
+		}
+
+		movies = append(movies, movie)
+		pending = append(pending, results[i])
+	}
+
+	matches, err := app.models.Movies.MatchExternalIDs(movies)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	ownOnly, err := app.ownOnly(request, "movies:write")
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	var ownerID int64
+	if ownOnly {
+		ownerID = app.contextGetUser(request).ID
+	}
+
+	movies, pending = matchBulkRecords(matches, movies, pending, ownerID)
+
+	if !force {
+		movies, pending, err = app.rejectBulkDuplicates(request, movies, pending)
+		if err != nil {
+			app.serverErrorResponse(writer, request, err)
+			return
+		}
+	}
+
+	if mode == "atomic" && len(movies) != len(records) {
+		app.errorResponse(writer, request, http.StatusUnprocessableEntity, map[string]any{"results": invalidResults(results)})
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	// Atomic imports go through a single transaction. Best-effort imports commit each
+	// batch on its own, so a failing batch doesn't undo the ones before it.
+	batchSize := len(movies)
+	if mode == "best_effort" {
+		batchSize = bulkBatchSize
+	}
+
+	for start := 0; start < len(movies); start += batchSize {
+		end := min(start+batchSize, len(movies))
+
+		err := app.models.Movies.UpsertMany(movies[start:end], user.ID)
+		if err != nil && mode == "atomic" {
+			switch {
+			case errors.Is(err, data.ErrEditConflict), errors.Is(err, data.ErrDuplicateExternalID):
+				app.editConflictResponse(writer, request)
+			default:
+				app.serverErrorResponse(writer, request, err)
+			}
+			return
+		}
+
+		for i := start; i < end; i++ {
+			if err != nil {
+				app.logError(request, err)
+				pending[i].Status = "failed"
+				pending[i].Errors = map[string]string{"record": "could not be saved, please try again"}
+				continue
+			}
+
+			pending[i].Status = "created"
+			if pending[i].ID != 0 {
+				pending[i].Status = "updated"
+			}
+
+			pending[i].ID = movies[i].ID
+		}
+	}
+
+	summary := map[string]int{"total": len(results)}
+	for _, result := range results {
+		summary[result.Status]++
+	}
+
+	status := http.StatusOK
+	if summary["created"] == len(results) {
+		status = http.StatusCreated
+	}
+
+	err = app.writeJSON(writer, status, map[string]any{"summary": summary, "results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// matchBulkRecords points each record sharing an external ID with an existing movie at
+// that movie, setting the ID of both and the version the movie is expected to be at.
+// Records matching more than one movie or a movie in the trash, matching a movie not
+// created by a non-zero ownerID, or claiming an external ID or a movie already claimed
+// by an earlier record, are marked invalid and left out of the returned records.
+func matchBulkRecords(matches data.ExternalIDMatches, movies []*data.Movie, pending []*bulkResult, ownerID int64) ([]*data.Movie, []*bulkResult) {
+	claimed := map[string]int{}
+	matchedMovies, matchedPending := []*data.Movie{}, []*bulkResult{}
+
+	for i, movie := range movies {
+		existing := matches.Movies(movie.ExternalIDs)
+		if len(existing) > 1 {
+			ids := make([]int64, len(existing))
+			for j, match := range existing {
+				ids[j] = match.MovieID
+			}
+
+			pending[i].Status = "invalid"
+			pending[i].Errors = map[string]string{"external_ids": fmt.Sprintf("match more than one existing movie: %v", ids)}
+			continue
+		}
+
+		if len(existing) == 1 && existing[0].Trashed {
+			pending[i].Status = "invalid"
+			pending[i].Errors = map[string]string{"external_ids": fmt.Sprintf("match movie %d, which is in the trash", existing[0].MovieID)}
+			continue
+		}
+
+		if len(existing) == 1 && ownerID != 0 && existing[0].CreatedBy != ownerID {
+			pending[i].Status = "invalid"
+			pending[i].Errors = map[string]string{"external_ids": fmt.Sprintf("match movie %d, which you don't own", existing[0].MovieID)}
+			continue
+		}
+
+		claims := []string{}
+		for source, id := range movie.ExternalIDs {
+			claims = append(claims, source+":"+id)
+		}
+
+		if len(existing) == 1 {
+			claims = append(claims, fmt.Sprintf("movie:%d", existing[0].MovieID))
+		}
+
+		conflict := -1
+		for _, claim := range claims {
+			if index, ok := claimed[claim]; ok && (conflict < 0 || index < conflict) {
+				conflict = index
+			}
+		}
+
+		if conflict >= 0 {
+			pending[i].Status = "invalid"
+			pending[i].Errors = map[string]string{"external_ids": fmt.Sprintf("match the same movie as record %d", conflict)}
+			continue
+		}
+
+		for _, claim := range claims {
+			claimed[claim] = pending[i].Index
+		}
+
+		if len(existing) == 1 {
+			movie.ID, movie.Version = existing[0].MovieID, existing[0].Version
+			pending[i].ID = existing[0].MovieID
+		}
+
+		matchedMovies = append(matchedMovies, movie)
+		matchedPending = append(matchedPending, pending[i])
+	}
+
+	return matchedMovies, matchedPending
+}
+
+// rejectBulkDuplicates marks the records that would create a likely duplicate of an
+// existing movie invalid, and leaves them out of the returned records. Records updating
+// an existing movie aren't checked.
+func (app *application) rejectBulkDuplicates(request *http.Request, movies []*data.Movie, pending []*bulkResult) ([]*data.Movie, []*bulkResult, error) {
+	created := []*data.Movie{}
+	for _, movie := range movies {
+		if movie.ID == 0 {
+			created = append(created, movie)
+		}
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates, err := app.models.Movies.FindDuplicatesMany(created, !canSeeUnpublished, app.contextGetUser(request).ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keptMovies, keptPending := []*data.Movie{}, []*bulkResult{}
+
+	next := 0
+	for i, movie := range movies {
+		if movie.ID == 0 {
+			duplicates := candidates[next]
+			next++
+
+			if len(duplicates) > 0 {
+				ids := make([]int64, len(duplicates))
+				for j, duplicate := range duplicates {
+					ids[j] = duplicate.ID
+				}
+
+				pending[i].Status = "invalid"
+				pending[i].Errors = map[string]string{"title": fmt.Sprintf("may duplicate existing movies %v, use force=true to import it anyway", ids)}
+				continue
+			}
+		}
+
+		keptMovies = append(keptMovies, movie)
+		keptPending = append(keptPending, pending[i])
+	}
+
+	return keptMovies, keptPending, nil
+}
+
+func invalidResults(results []*bulkResult) []*bulkResult {
+	invalid := []*bulkResult{}
+
+	for _, result := range results {
+		if result.Status == "invalid" {
+			invalid = append(invalid, result)
+		}
+	}
+
+	return invalid
+}
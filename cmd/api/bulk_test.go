@@ -0,0 +1,137 @@
+package main
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+)
+
+func TestMatchBulkRecords(t *testing.T) {
+	matches := data.ExternalIDMatches{
+		"imdb": {
+			"tt1": {MovieID: 1, Version: 3, CreatedBy: 10},
+			"tt2": {MovieID: 2, Version: 1, CreatedBy: 20},
+			"tt9": {MovieID: 9, Version: 2, CreatedBy: 10, Trashed: true},
+		},
+		"tmdb": {
+			"100": {MovieID: 1, Version: 3, CreatedBy: 10},
+			"200": {MovieID: 2, Version: 1, CreatedBy: 20},
+		},
+	}
+
+	type want struct {
+		status  string
+		id      int64
+		version int32
+		err     string
+	}
+
+	tests := []struct {
+		name    string
+		records []data.ExternalIDs
+		ownerID int64
+		want    []want
+	}{
+		{
+			name:    "new movies",
+			records: []data.ExternalIDs{{"imdb": "tt5"}, nil},
+			want:    []want{{}, {}},
+		},
+		{
+			name:    "update existing movie",
+			records: []data.ExternalIDs{{"imdb": "tt1", "tmdb": "100"}},
+			want:    []want{{id: 1, version: 3}},
+		},
+		{
+			name:    "ids matching different movies",
+			records: []data.ExternalIDs{{"imdb": "tt1", "tmdb": "200"}},
+			want:    []want{{status: "invalid", err: "match more than one existing movie: [1 2]"}},
+		},
+		{
+			name:    "movie in the trash",
+			records: []data.ExternalIDs{{"imdb": "tt9"}},
+			want:    []want{{status: "invalid", err: "match movie 9, which is in the trash"}},
+		},
+		{
+			name:    "own movie",
+			records: []data.ExternalIDs{{"imdb": "tt1"}},
+			ownerID: 10,
+			want:    []want{{id: 1, version: 3}},
+		},
+		{
+			name:    "other user's movie",
+			records: []data.ExternalIDs{{"imdb": "tt2"}},
+			ownerID: 10,
+			want:    []want{{status: "invalid", err: "match movie 2, which you don't own"}},
+		},
+		{
+			name:    "two records updating one movie",
+			records: []data.ExternalIDs{{"imdb": "tt1"}, {"tmdb": "100"}},
+			want:    []want{{id: 1, version: 3}, {status: "invalid", err: "match the same movie as record 0"}},
+		},
+		{
+			name:    "two records sharing a new external id",
+			records: []data.ExternalIDs{{"imdb": "tt5"}, {"imdb": "tt6"}, {"imdb": "tt5", "tmdb": "500"}},
+			want:    []want{{}, {}, {status: "invalid", err: "match the same movie as record 0"}},
+		},
+		{
+			name:    "invalid record claims nothing",
+			records: []data.ExternalIDs{{"imdb": "tt1", "tmdb": "200"}, {"imdb": "tt1"}},
+			want:    []want{{status: "invalid", err: "match more than one existing movie: [1 2]"}, {id: 1, version: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movies := make([]*data.Movie, len(tt.records))
+			pending := make([]*bulkResult, len(tt.records))
+
+			for i, ids := range tt.records {
+				movies[i] = &data.Movie{Title: "Moana", ExternalIDs: ids}
+				pending[i] = &bulkResult{Index: i}
+			}
+
+			matchedMovies, matchedPending := matchBulkRecords(matches, movies, pending, tt.ownerID)
+
+			if len(matchedMovies) != len(matchedPending) {
+				t.Fatalf("got %d movies and %d results", len(matchedMovies), len(matchedPending))
+			}
+
+			matched := map[int]bool{}
+			for i, result := range matchedPending {
+				matched[result.Index] = true
+
+				if matchedMovies[i] != movies[result.Index] {
+					t.Errorf("result %d is paired with the wrong movie", result.Index)
+				}
+			}
+
+			for i, want := range tt.want {
+				result, movie := pending[i], movies[i]
+
+				if result.Status != want.status {
+					t.Errorf("record %d: got status %q; want %q", i, result.Status, want.status)
+				}
+
+				if matched[i] != (want.status == "") {
+					t.Errorf("record %d: got returned %t; want %t", i, matched[i], want.status == "")
+				}
+
+				if result.ID != want.id || movie.ID != want.id || movie.Version != want.version {
+					t.Errorf("record %d: got result ID %d, movie ID %d, version %d; want ID %d, version %d",
+						i, result.ID, movie.ID, movie.Version, want.id, want.version)
+				}
+
+				wantErrors := map[string]string(nil)
+				if want.err != "" {
+					wantErrors = map[string]string{"external_ids": want.err}
+				}
+
+				if !maps.Equal(result.Errors, wantErrors) {
+					t.Errorf("record %d: got errors %v; want %v", i, result.Errors, wantErrors)
+				}
+			}
+		})
+	}
+}
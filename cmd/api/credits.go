@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listMovieCredits(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	if _, ok := app.readVisibleMovie(writer, request, movieId); !ok {
+		return
+	}
+
+	credits, err := app.models.Credits.GetAllForMovie(movieId)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"credits": credits}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) createMovieCredit(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		PersonID  int64  `json:"person_id"`
+		Role      string `json:"role"`
+		Character string `json:"character"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	credit := &data.Credit{
+		MovieID:   movieId,
+		PersonID:  input.PersonID,
+		Role:      input.Role,
+		Character: input.Character,
+	}
+
+	val := validator.New()
+	if data.ValidateCredit(val, credit); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, err := app.models.Movies.Get(movieId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	if err := app.models.Credits.Insert(credit); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("person_id", "must be an existing person")
+			app.failedValidationResponse(writer, request, val.Errors)
+		case errors.Is(err, data.ErrDuplicateCredit):
+			val.AddError("person_id", "is already credited in this role")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusCreated, map[string]any{"credit": credit}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) deleteMovieCredit(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	creditId, err := app.readIntParam(request, "credit_id")
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	if err := app.models.Credits.Delete(movieId, creditId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"message": "credit successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
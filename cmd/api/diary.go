@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listDiary(writer http.ResponseWriter, request *http.Request) {
+	var filters data.Filters
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	year := app.readInt(query, "year", 0, val)
+
+	filters.Page = app.readInt(query, "page", 1, val)
+	filters.PageSize = app.readInt(query, "page_size", 20, val)
+
+	// A year of 0, the default, lists every year.
+	if year != 0 {
+		data.ValidateDiaryYear(val, year)
+	}
+
+	if data.ValidatePagination(val, filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	entries, metadata, err := app.models.Diary.GetAllForUser(user.ID, year, filters, !canSeeUnpublished)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	for _, entry := range entries {
+		app.setRuntimeFormat(request, entry.Movie)
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "diary": entries}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) createDiaryEntry(writer http.ResponseWriter, request *http.Request) {
+	var input struct {
+		MovieID   int64  `json:"movie_id"`
+		WatchedOn string `json:"watched_on"`
+		Rating    *int   `json:"rating"`
+		Notes     string `json:"notes"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	entry := &data.DiaryEntry{
+		MovieID:   input.MovieID,
+		WatchedOn: input.WatchedOn,
+		Rating:    input.Rating,
+		Notes:     input.Notes,
+	}
+
+	val := validator.New()
+	if data.ValidateDiaryEntry(val, entry); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(entry.MovieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("movie_id", "must be an existing movie")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	visible, err := app.canSeeMovie(request, movie)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	if val.Check(visible, "movie_id", "must be an existing movie"); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Diary.Insert(user.ID, entry); err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	entry.Movie = movie
+	app.setRuntimeFormat(request, entry.Movie)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/users/me/diary/%d", entry.ID))
+
+	err = app.writeJSON(writer, http.StatusCreated, map[string]any{"entry": entry}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) deleteDiaryEntry(writer http.ResponseWriter, request *http.Request) {
+	entryId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Diary.Delete(user.ID, entryId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"message": "diary entry successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// showDiaryStats summarises the current user's viewings for a year, defaulting to the
+// current one.
+func (app *application) showDiaryStats(writer http.ResponseWriter, request *http.Request) {
+	val := validator.New()
+	query := request.URL.Query()
+
+	year := app.readInt(query, "year", time.Now().Year(), val)
+	topGenres := app.readInt(query, "top_genres", 5, val)
+
+	data.ValidateDiaryYear(val, year)
+	val.Check(topGenres > 0, "top_genres", "must be greater than zero")
+	val.Check(topGenres <= 20, "top_genres", "must be a maximum of 20")
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	stats, err := app.models.Diary.Stats(user.ID, year, topGenres, !canSeeUnpublished)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	stats.RuntimeFormat = app.readRuntimeFormat(request)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
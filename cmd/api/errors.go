@@ -78,3 +78,13 @@ func (app *application) notPermittedResponse(writer http.ResponseWriter, request
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(writer, request, http.StatusForbidden, message)
 }
+
+func (app *application) preconditionFailedResponse(writer http.ResponseWriter, request *http.Request) {
+	message := "the resource has been modified since it was last fetched, please fetch it again"
+	app.errorResponse(writer, request, http.StatusPreconditionFailed, message)
+}
+
+func (app *application) unsupportedMediaTypeResponse(writer http.ResponseWriter, request *http.Request) {
+	message := fmt.Sprintf("the %q content type is not supported for this resource", request.Header.Get("Content-Type"))
+	app.errorResponse(writer, request, http.StatusUnsupportedMediaType, message)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+// exportFlushEvery is the number of rows written between flushes to the client.
+const exportFlushEvery = 100
+
+// exportMovies streams the movies matching the listing filters as CSV or NDJSON.
+// Rows are written as they are read from the database, so the whole catalogue is
+// never held in memory.
+func (app *application) exportMovies(writer http.ResponseWriter, request *http.Request) {
+	val := validator.New()
+	query := request.URL.Query()
+
+	format := app.readString(query, "format", "csv")
+	criteria := app.readMovieCriteria(request, val)
+
+	// Without a runtime format, CSV runtimes stay a bare number of minutes.
+	runtimeFormat := app.readRuntimeFormat(request)
+
+	val.Check(validator.PermittedValue(format, "csv", "ndjson"), "format", "must be csv or ndjson")
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	criteria.PublishedOnly = !canSeeUnpublished
+	criteria.Viewer = app.contextGetUser(request).ID
+
+	// The export can outlast the server's write timeout, so lift it for this response.
+	controller := http.NewResponseController(writer)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	var write func(*data.Movie) error
+	var flush func() error
+
+	switch format {
+	case "csv":
+		csvWriter := csv.NewWriter(writer)
+
+		write = func(movie *data.Movie) error {
+			runtime := strconv.Itoa(int(movie.Runtime))
+			if runtimeFormat != "" {
+				runtime = movie.Runtime.Text(runtimeFormat)
+			}
+
+			return csvWriter.Write([]string{
+				strconv.FormatInt(movie.ID, 10),
+				movie.Title,
+				strconv.Itoa(int(movie.Year)),
+				runtime,
+				strings.Join(movie.Genres, "|"),
+				strconv.Itoa(int(movie.Version)),
+				movie.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+
+		writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer.Header().Set("Content-Disposition", `attachment; filename="movies.csv"`)
+		writer.WriteHeader(http.StatusOK)
+
+		if err := csvWriter.Write([]string{"id", "title", "year", "runtime", "genres", "version", "created_at"}); err != nil {
+			app.logError(request, err)
+			return
+		}
+
+	case "ndjson":
+		encoder := json.NewEncoder(writer)
+
+		write = func(movie *data.Movie) error {
+			movie.RuntimeFormat = runtimeFormat
+			return encoder.Encode(movie)
+		}
+		flush = func() error {
+			return nil
+		}
+
+		writer.Header().Set("Content-Type", "application/x-ndjson")
+		writer.Header().Set("Content-Disposition", `attachment; filename="movies.ndjson"`)
+		writer.WriteHeader(http.StatusOK)
+	}
+
+	count := 0
+
+	err = app.models.Movies.Export(request.Context(), criteria, func(movie *data.Movie) error {
+		if err := write(movie); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+
+			return controller.Flush()
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	// The status line has already been sent, so a failure part way through can only
+	// be logged; the client sees a truncated export.
+	if err != nil {
+		app.logError(request, err)
+	}
+}
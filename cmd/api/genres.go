@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listGenres(writer http.ResponseWriter, request *http.Request) {
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	genres, err := app.models.Genres.GetAll(!canSeeUnpublished)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"genres": genres}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) createGenre(writer http.ResponseWriter, request *http.Request) {
+	var input struct {
+		Name    string   `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	genre := &data.Genre{
+		Name:    strings.TrimSpace(input.Name),
+		Aliases: input.Aliases,
+	}
+
+	if genre.Aliases == nil {
+		genre.Aliases = []string{}
+	}
+
+	vocabulary, err := app.models.Genres.Vocabulary()
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	val := validator.New()
+	data.ValidateGenre(val, genre)
+	app.checkGenreVocabulary(val, genre, "", vocabulary)
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if err := app.models.Genres.Insert(genre); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateGenre):
+			val.AddError("name", "a genre with this name or alias already exists")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/genres/%d", genre.ID))
+
+	err = app.writeJSON(writer, http.StatusCreated, map[string]any{"genre": genre}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// updateGenre renames a genre and/or replaces its aliases. A rename is applied to
+// every movie using the genre.
+func (app *application) updateGenre(writer http.ResponseWriter, request *http.Request) {
+	genreId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	genre, err := app.models.Genres.Get(genreId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name    *string  `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	oldName := genre.Name
+
+	if input.Name != nil {
+		genre.Name = strings.TrimSpace(*input.Name)
+	}
+
+	if input.Aliases != nil {
+		genre.Aliases = input.Aliases
+	}
+
+	vocabulary, err := app.models.Genres.Vocabulary()
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	val := validator.New()
+	data.ValidateGenre(val, genre)
+	app.checkGenreVocabulary(val, genre, oldName, vocabulary)
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Genres.Update(genre, oldName, user.ID); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateGenre):
+			val.AddError("name", "a genre with this name or alias already exists")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"genre": genre}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// mergeGenre folds the genre in the URL into the target genre given in the body.
+func (app *application) mergeGenre(writer http.ResponseWriter, request *http.Request) {
+	genreId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		Into int64 `json:"into"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	val := validator.New()
+	val.Check(input.Into > 0, "into", "must be provided")
+	val.Check(input.Into != genreId, "into", "must be a different genre")
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	genres := make([]*data.Genre, 2)
+
+	for i, id := range []int64{genreId, input.Into} {
+		genres[i], err = app.models.Genres.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound) && i == 0:
+				app.notFoundResponse(writer, request)
+			case errors.Is(err, data.ErrRecordNotFound):
+				val.AddError("into", "must be an existing genre")
+				app.failedValidationResponse(writer, request, val.Errors)
+			default:
+				app.serverErrorResponse(writer, request, err)
+			}
+			return
+		}
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Genres.Merge(genres[0], genres[1], user.ID); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		case errors.Is(err, data.ErrMergeTarget):
+			val.AddError("into", "must be an existing genre")
+			app.failedValidationResponse(writer, request, val.Errors)
+		case errors.Is(err, data.ErrDuplicateGenre):
+			val.AddError("into", "a genre with this name or alias already exists")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	target, err := app.models.Genres.Get(input.Into)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"genre": target}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// checkGenreVocabulary checks that the genre's name and aliases aren't already used by
+// another genre. currentName identifies the genre being edited, if any.
+func (app *application) checkGenreVocabulary(val *validator.Validator, genre *data.Genre, currentName string, vocabulary data.GenreVocabulary) {
+	taken := func(name string) bool {
+		canonical, ok := vocabulary.Canonical(name)
+		return ok && (currentName == "" || !strings.EqualFold(canonical, currentName))
+	}
+
+	val.Check(!taken(genre.Name), "name", "is already used by another genre")
+
+	for _, alias := range genre.Aliases {
+		val.Check(!taken(alias), "aliases", fmt.Sprintf("%q is already used by another genre", alias))
+	}
+}
@@ -5,19 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sparrowsl/greenlight/internal/data"
 	"github.com/sparrowsl/greenlight/internal/validator"
 )
 
 func (app *application) readIDParam(request *http.Request) (int64, error) {
-	id, err := strconv.Atoi(chi.URLParam(request, "id"))
+	return app.readIntParam(request, "id")
+}
+
+// The readIntParam() helper reads a positive integer URL parameter, such as a nested
+// resource id or a version number.
+func (app *application) readIntParam(request *http.Request, key string) (int64, error) {
+	id, err := strconv.Atoi(chi.URLParam(request, key))
 	if err != nil || id < 1 {
-		return 0, errors.New("Invalid id parameter")
+		return 0, fmt.Errorf("Invalid %s parameter", key)
 	}
 
 	return int64(id), nil
@@ -42,6 +53,25 @@ func (app *application) writeJSON(writer http.ResponseWriter, status int, data m
 	return nil
 }
 
+// The readRuntimeFormat() helper returns the format the client asked for runtimes to
+// be written in, which the runtimeFormat middleware has already checked. It is empty
+// when the client didn't ask for one.
+func (app *application) readRuntimeFormat(request *http.Request) data.RuntimeFormat {
+	return data.RuntimeFormat(request.URL.Query().Get("runtime_format"))
+}
+
+// The setRuntimeFormat() helper makes the movies be written with their runtimes in
+// the format the client asked for. Nil movies are skipped.
+func (app *application) setRuntimeFormat(request *http.Request, movies ...*data.Movie) {
+	format := app.readRuntimeFormat(request)
+
+	for _, movie := range movies {
+		if movie != nil {
+			movie.RuntimeFormat = format
+		}
+	}
+}
+
 func (app *application) readJSON(writer http.ResponseWriter, request *http.Request, dest any) error {
 	maxBytes := 1_048_576 // limit for the request body size
 	request.Body = http.MaxBytesReader(writer, request.Body, int64(maxBytes))
@@ -49,50 +79,114 @@ func (app *application) readJSON(writer http.ResponseWriter, request *http.Reque
 	dec := json.NewDecoder(request.Body)
 	dec.DisallowUnknownFields()
 
-	err := dec.Decode(dest)
-	if err != nil {
-		var syntaxError *json.SyntaxError
-		var unmarshalTypeError *json.UnmarshalTypeError
-		var invalidUnmarshalError *json.InvalidUnmarshalError
-		var maxBytesError *http.MaxBytesError
+	if err := dec.Decode(dest); err != nil {
+		return app.jsonError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errors.New("body must only contain a single JSON value")
+	}
 
-		switch {
-		case errors.As(err, &syntaxError):
-			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+	return nil
+}
 
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("body contains badly-formed JSON")
+// The jsonError() helper turns an error from decoding a request body into a message
+// that is safe and useful to send back to the client.
+func (app *application) jsonError(err error) error {
+	var syntaxError *json.SyntaxError
+	var unmarshalTypeError *json.UnmarshalTypeError
+	var invalidUnmarshalError *json.InvalidUnmarshalError
+	var maxBytesError *http.MaxBytesError
 
-		case errors.As(err, &unmarshalTypeError):
-			if unmarshalTypeError.Field != "" {
-				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
-			}
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body contains badly-formed JSON")
+
+	case errors.As(err, &unmarshalTypeError):
+		if unmarshalTypeError.Field != "" {
+			return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+		}
+
+		return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Errorf("body contains unknown key %s", fieldName)
+
+	case errors.As(err, &maxBytesError): // optional limit for the request body
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+
+	case errors.As(err, &invalidUnmarshalError):
+		panic(err)
+
+	default:
+		return err
+	}
+}
 
-			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+// The readJSONRecords() helper reads a request body holding many JSON values, sent
+// either as a single JSON array or, with an application/x-ndjson content type, as
+// newline-delimited JSON. The records are returned undecoded so that the caller can
+// report decoding errors for each record separately.
+func (app *application) readJSONRecords(writer http.ResponseWriter, request *http.Request, maxBytes int64, maxRecords int) ([]json.RawMessage, error) {
+	request.Body = http.MaxBytesReader(writer, request.Body, maxBytes)
 
-		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
+	dec := json.NewDecoder(request.Body)
+	records := []json.RawMessage{}
+
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	ndjson := mediaType == "application/x-ndjson" || mediaType == "application/ndjson"
+
+	if !ndjson {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, app.jsonError(err)
+		}
 
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("body must be a JSON array")
+		}
+	}
 
-		case errors.As(err, &maxBytesError): // optional limit for the request body
-			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+	for ndjson || dec.More() {
+		var record json.RawMessage
 
-		case errors.As(err, &invalidUnmarshalError):
-			panic(err)
+		err := dec.Decode(&record)
+		if ndjson && errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, app.jsonError(err)
+		}
 
-		default:
-			return err
+		if len(records) == maxRecords {
+			return nil, fmt.Errorf("body must not contain more than %d records", maxRecords)
 		}
+
+		records = append(records, record)
 	}
 
-	if err := dec.Decode(&struct{}{}); err != io.EOF {
-		return errors.New("body must only contain a single JSON value")
+	if !ndjson {
+		if _, err := dec.Token(); err != nil {
+			return nil, app.jsonError(err)
+		}
+
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, errors.New("body must only contain a single JSON array")
+		}
 	}
 
-	return nil
+	if len(records) == 0 {
+		return nil, errors.New("body must contain at least one record")
+	}
+
+	return records, nil
 }
 
 // The readString() helper returns a string value from the query string, or the provided
@@ -138,6 +232,141 @@ func (app *application) readInt(query url.Values, key string, defaultValue int,
 	return i
 }
 
+// The readBool() helper returns a boolean value from the query string, or the provided
+// default value if the key is missing or the value isn't a valid boolean.
+func (app *application) readBool(query url.Values, key string, defaultValue bool, validator *validator.Validator) bool {
+	s := query.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		validator.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+// The readOptionalInt() helper behaves like readInt() but returns nil when the key is
+// missing, so that callers can tell an absent bound apart from an explicit zero.
+func (app *application) readOptionalInt(query url.Values, key string, validator *validator.Validator) *int {
+	if query.Get(key) == "" {
+		return nil
+	}
+
+	i := app.readInt(query, key, 0, validator)
+	return &i
+}
+
+// The readTime() helper returns a timestamp from the query string, accepting either
+// an RFC 3339 timestamp or a plain YYYY-MM-DD date (taken as midnight UTC). It returns
+// nil if the key is missing or the value can't be parsed.
+func (app *application) readTime(query url.Values, key string, validator *validator.Validator) *time.Time {
+	s := query.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+
+	validator.AddError(key, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	return nil
+}
+
+// The readLocales() helper returns the locales the client prefers, most preferred
+// first: those in the lang query parameter if given, otherwise those in the
+// Accept-Language header ordered by quality. A regional tag is followed by its base
+// language, so that "fr-ca" falls back to "fr".
+func (app *application) readLocales(request *http.Request, validator *validator.Validator) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	tags := []weighted{}
+
+	if lang := request.URL.Query().Get("lang"); lang != "" {
+		for _, tag := range strings.Split(strings.ToLower(lang), ",") {
+			tag = strings.TrimSpace(tag)
+			validator.Check(data.LocaleRegex.MatchString(tag), "lang", "must contain valid language tags")
+			tags = append(tags, weighted{tag: tag, quality: 1})
+		}
+	} else {
+		for _, part := range strings.Split(request.Header.Get("Accept-Language"), ",") {
+			tag, params, _ := strings.Cut(strings.ToLower(strings.TrimSpace(part)), ";")
+			tag = strings.TrimSpace(tag)
+
+			// Malformed or wildcard entries in the header are ignored rather than
+			// rejected, since browsers send them on the user's behalf.
+			if !data.LocaleRegex.MatchString(tag) {
+				continue
+			}
+
+			quality := 1.0
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				parsed, err := strconv.ParseFloat(q, 64)
+				if err != nil {
+					continue
+				}
+				quality = parsed
+			}
+
+			if quality > 0 {
+				tags = append(tags, weighted{tag: tag, quality: quality})
+			}
+		}
+
+		sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+	}
+
+	locales := []string{}
+
+	for _, tag := range tags {
+		base, _, _ := strings.Cut(tag.tag, "-")
+
+		for _, locale := range []string{tag.tag, base} {
+			if !slices.Contains(locales, locale) {
+				locales = append(locales, locale)
+			}
+		}
+	}
+
+	return locales
+}
+
+// The etag() helper returns the entity tag of a movie. Its rating aggregates change
+// with its reviews rather than its version, so they are part of the tag too.
+func (app *application) etag(movie *data.Movie) string {
+	return fmt.Sprintf(`"%d-%d-%g"`, movie.Version, movie.RatingCount, movie.AverageRating)
+}
+
+// The matchETag() helper reports whether an If-Match or If-None-Match header value
+// matches etag. If-None-Match uses weak comparison, so W/ prefixed tags only match
+// when weak is true.
+func (app *application) matchETag(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 
@@ -154,3 +383,35 @@ func (app *application) background(fn func()) {
 		fn() // run the function to run in the background
 	}()
 }
+
+// every runs fn in the background straight away and then once per interval until the
+// server shuts down. A panic in one run is logged and doesn't stop the later ones. The
+// interval must be positive; the ticker is made before going into the background so
+// that a bad one panics at startup instead of being swallowed.
+func (app *application) every(interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+
+	run := func() {
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Println(fmt.Errorf("%s", err))
+			}
+		}()
+
+		fn()
+	}
+
+	app.background(func() {
+		defer ticker.Stop()
+
+		for {
+			run()
+
+			select {
+			case <-ticker.C:
+			case <-app.shutdown:
+				return
+			}
+		}
+	})
+}
@@ -16,6 +16,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/sparrowsl/greenlight/internal/data"
 	"github.com/sparrowsl/greenlight/internal/mailer"
+	"github.com/sparrowsl/greenlight/internal/storage"
 )
 
 const version = "1.0.0"
@@ -44,23 +45,35 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	trash struct {
+		retention     time.Duration // how long deleted movies are kept before purging
+		purgeInterval time.Duration
+	}
+	storage struct {
+		dir string // where uploaded images are kept
+	}
+	publishing struct {
+		interval time.Duration // how often scheduled movies are checked for publication
+	}
+	similarity data.SimilarityWeights
 }
 
 type application struct {
-	config config
-	logger *log.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config   config
+	logger   *log.Logger
+	models   data.Models
+	mailer   mailer.Mailer
+	storage  storage.Storage
+	wg       sync.WaitGroup
+	shutdown chan struct{} // closed when the server starts shutting down
 }
 
-func init() {
+func main() {
+	// Loaded here rather than in init so that the package's tests don't need a .env.
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
-}
 
-func main() {
 	var cfg config
 	flag.IntVar(&cfg.port, "port", 5000, "API Server Port")
 	flag.StringVar(&cfg.env, "env", "dev", "Environment (dev|staging|prod)")
@@ -85,10 +98,34 @@ func main() {
 		return nil
 	})
 
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", time.Hour*24*30, "How long deleted movies are kept before being purged (0 keeps them forever)")
+	flag.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", time.Hour, "How often expired movies are purged from the trash")
+
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory for uploaded images")
+
+	flag.DurationVar(&cfg.publishing.interval, "publish-interval", time.Minute, "How often movies scheduled for publication are published")
+
+	flag.Float64Var(&cfg.similarity.Genres, "similar-genres-weight", 0.6, "Weight of genre overlap when ranking similar movies")
+	flag.Float64Var(&cfg.similarity.Year, "similar-year-weight", 0.25, "Weight of year proximity when ranking similar movies")
+	flag.Float64Var(&cfg.similarity.Runtime, "similar-runtime-weight", 0.15, "Weight of runtime proximity when ranking similar movies")
+
 	flag.Parse()
 
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 
+	if cfg.similarity.Genres < 0 || cfg.similarity.Year < 0 || cfg.similarity.Runtime < 0 ||
+		cfg.similarity.Genres+cfg.similarity.Year+cfg.similarity.Runtime == 0 {
+		logger.Fatal("similarity weights must not be negative and must not all be zero")
+	}
+
+	if cfg.trash.purgeInterval <= 0 {
+		logger.Fatal("trash-purge-interval must be greater than zero")
+	}
+
+	if cfg.publishing.interval <= 0 {
+		logger.Fatal("publish-interval must be greater than zero")
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.Fatal(err)
@@ -97,6 +134,11 @@ func main() {
 
 	logger.Printf("database connection pool established...")
 
+	files, err := storage.NewLocal(cfg.storage.dir, "/v1/images/")
+	if err != nil {
+		logger.Fatal(err)
+	}
+
 	expvar.NewString("version").Set(version)
 	expvar.Publish("goroutines", expvar.Func(func() any {
 		return runtime.NumGoroutine()
@@ -109,12 +151,17 @@ func main() {
 	}))
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModel(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:   cfg,
+		logger:   logger,
+		models:   data.NewModel(db),
+		mailer:   mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		storage:  files,
+		shutdown: make(chan struct{}),
 	}
 
+	app.purgeTrash()
+	app.publishScheduled()
+
 	if err := app.serve(); err != nil {
 		logger.Fatal(err)
 	}
@@ -143,6 +143,17 @@ func (app *application) requireActivatedUser(next http.Handler) http.Handler {
 }
 
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	return app.requireOwnPermission(code, nil, next)
+}
+
+// ownerFunc returns the ID of the user owning the resource a request acts on.
+type ownerFunc func(request *http.Request) (int64, error)
+
+// requireOwnPermission is requirePermission for routes acting on a resource with an
+// owner: a user without code is still let through if they hold its ":own" variant,
+// such as movies:write:own, and owner says the resource is theirs. With a nil owner
+// only code itself is accepted.
+func (app *application) requireOwnPermission(code string, owner ownerFunc, next http.HandlerFunc) http.HandlerFunc {
 	fn := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		user := app.contextGetUser(request)
 
@@ -152,7 +163,28 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 			return
 		}
 
-		if !permissions.Include(code) {
+		if permissions.Include(code) {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		if owner == nil || !permissions.Include(code+":own") {
+			app.notPermittedResponse(writer, request)
+			return
+		}
+
+		ownerID, err := owner(request)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(writer, request)
+			default:
+				app.serverErrorResponse(writer, request, err)
+			}
+			return
+		}
+
+		if ownerID != user.ID {
 			app.notPermittedResponse(writer, request)
 			return
 		}
@@ -163,6 +195,34 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 	return app.requireActivatedUser(fn).(http.HandlerFunc)
 }
 
+// movieOwner is the ownerFunc for routes acting on the movie in the id parameter.
+func (app *application) movieOwner(request *http.Request) (int64, error) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		return 0, data.ErrRecordNotFound
+	}
+
+	return app.models.Movies.GetOwner(movieId)
+}
+
+// creatorOwns is the ownerFunc for routes creating a resource, which the user creating
+// it will own, and for routes whose handlers limit themselves to the user's own
+// resources when ownOnly says so.
+func (app *application) creatorOwns(request *http.Request) (int64, error) {
+	return app.contextGetUser(request).ID, nil
+}
+
+// ownOnly reports whether the user was let through requireOwnPermission by the ":own"
+// variant of code alone, and so may only act on resources they own.
+func (app *application) ownOnly(request *http.Request, code string) (bool, error) {
+	permissions, err := app.models.Permissions.GetAllForUser(app.contextGetUser(request).ID)
+	if err != nil {
+		return false, err
+	}
+
+	return !permissions.Include(code), nil
+}
+
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		writer.Header().Add("Vary", "Origin")
@@ -174,10 +234,11 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 			for _, i := range app.config.cors.trustedOrigins {
 				if origin == i {
 					writer.Header().Set("Access-Control-Allow-Origin", origin)
+					writer.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 					if request.Method == http.MethodOptions && request.Header.Get("Access-Control-Request-Method") != "" {
 						writer.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match")
 
 						writer.WriteHeader(http.StatusOK)
 						return
@@ -213,3 +274,18 @@ func (app *application) metrics(next http.Handler) http.Handler {
 	})
 
 }
+
+// runtimeFormat checks the runtime_format query parameter that selects how runtimes
+// are written in responses, so that handlers can read it with readRuntimeFormat.
+func (app *application) runtimeFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		format := app.readRuntimeFormat(request)
+
+		if format != "" && !validator.PermittedValue(format, data.RuntimeFormats...) {
+			app.failedValidationResponse(writer, request, map[string]string{"runtime_format": "must be mins, hm, iso8601 or seconds"})
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
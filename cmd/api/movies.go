@@ -1,26 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/jsonpatch"
 	"github.com/sparrowsl/greenlight/internal/validator"
 )
 
 func (app *application) listAllMovies(writer http.ResponseWriter, request *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieCriteria
 		data.Filters
 	}
 
 	val := validator.New()
 	query := request.URL.Query()
 
-	input.Title = app.readString(query, "title", "")
-	input.Genres = app.readCSV(query, "genres", []string{})
+	input.MovieCriteria = app.readMovieCriteria(request, val)
 
 	input.Filters.Page = app.readInt(query, "page", 1, val)
 	input.Filters.PageSize = app.readInt(query, "page_size", 20, val)
@@ -28,20 +31,205 @@ func (app *application) listAllMovies(writer http.ResponseWriter, request *http.
 	// Extract the sort query string value, falling back to "id" i
 	// by the client (which will imply a ascending sort on movie I
 	input.Filters.Sort = app.readString(query, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime", "relevance", "rating", "-rating"}
+	input.Filters.Fields = app.readCSV(query, "fields", []string{})
+	input.Filters.FieldSafelist = data.MovieFieldSafelist
+
+	// Passing a cursor parameter (empty for the first page) switches the listing to
+	// keyset pagination; page/page_size paging is kept for older clients.
+	input.Filters.UseCursor = query.Has("cursor")
+	input.Filters.Cursor = app.readString(query, "cursor", "")
+
+	val.Check(!validator.PermittedValue("relevance", strings.Split(input.Sort, ",")...) || input.Title != "", "sort", "relevance requires a title search")
+
+	facets := app.readCSV(query, "facets", []string{})
+
+	for _, facet := range facets {
+		val.Check(validator.PermittedValue(facet, data.FacetSafelist...), "facets", "must only contain genres, decade or runtime_bucket")
+	}
+
+	val.Check(validator.Unique(facets), "facets", "must not contain duplicate values")
+
+	locales := app.readLocales(request, val)
 
 	if data.ValidateFilters(val, input.Filters); !val.Valid() {
 		app.failedValidationResponse(writer, request, val.Errors)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	input.PublishedOnly = !canSeeUnpublished
+	input.Viewer = app.contextGetUser(request).ID
+
+	movies, metadata, counts, err := app.models.Movies.GetAll(input.MovieCriteria, input.Filters, facets)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	if err := app.models.Titles.Localize(movies, locales); err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Vary", "Accept-Language")
+
+	app.setRuntimeFormat(request, movies...)
+
+	envelope := map[string]any{"metadata": metadata, "movies": movies}
+
+	if len(input.Fields) > 0 {
+		projections := make([]map[string]any, len(movies))
+		for i, movie := range movies {
+			projections[i] = movie.Project(input.Fields)
+		}
+
+		envelope["movies"] = projections
+	}
+
+	if counts != nil {
+		envelope["facets"] = counts
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, envelope, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// readMovieCriteria reads and validates the search parameters shared by the movie
+// listing and export endpoints.
+func (app *application) readMovieCriteria(request *http.Request, val *validator.Validator) data.MovieCriteria {
+	var criteria data.MovieCriteria
+
+	query := request.URL.Query()
+
+	criteria.Title = app.readString(query, "title", "")
+	criteria.Genres = app.readCSV(query, "genres", []string{})
+
+	criteria.YearMin = app.readOptionalInt(query, "year_min", val)
+	criteria.YearMax = app.readOptionalInt(query, "year_max", val)
+	criteria.RuntimeMin = app.readOptionalInt(query, "runtime_min", val)
+	criteria.RuntimeMax = app.readOptionalInt(query, "runtime_max", val)
+	criteria.CreatedAfter = app.readTime(query, "created_after", val)
+	criteria.CreatedBefore = app.readTime(query, "created_before", val)
+	criteria.MinRating = app.readOptionalInt(query, "min_rating", val)
+	criteria.Person = app.readOptionalInt(query, "person", val)
+	criteria.Role = app.readString(query, "role", "")
+	criteria.Statuses = app.readCSV(query, "status", []string{})
+	criteria.CreatedBy = app.readCreatedBy(request, val)
+	criteria.Fuzzy = app.readBool(query, "fuzzy", false, val)
+	criteria.Highlight = app.readBool(query, "highlight", false, val)
+
+	data.ValidateMovieCriteria(val, criteria)
+	val.Check(!criteria.Highlight || criteria.Title != "", "highlight", "requires a title search")
+	val.Check(!criteria.Highlight || !criteria.Fuzzy, "highlight", "is not supported for fuzzy searches")
+
+	return criteria
+}
+
+// readCreatedBy reads the created_by filter, either a user ID or "me" for the current
+// user. It returns nil if the filter wasn't given.
+func (app *application) readCreatedBy(request *http.Request, val *validator.Validator) *int64 {
+	query := request.URL.Query()
+
+	if query.Get("created_by") == "me" {
+		id := app.contextGetUser(request).ID
+		return &id
+	}
+
+	createdBy := app.readOptionalInt(query, "created_by", val)
+	if createdBy == nil {
+		return nil
+	}
+
+	id := int64(*createdBy)
+	return &id
+}
+
+func (app *application) suggestMovies(writer http.ResponseWriter, request *http.Request) {
+	val := validator.New()
+	query := request.URL.Query()
+
+	prefix := app.readString(query, "q", "")
+	limit := app.readInt(query, "limit", 10, val)
+
+	val.Check(prefix != "", "q", "must be provided")
+	val.Check(len(prefix) <= 100, "q", "must not be more than 100 bytes long")
+	val.Check(limit > 0, "limit", "must be greater than zero")
+	val.Check(limit <= 20, "limit", "must be a maximum of 20")
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	suggestions, err := app.models.Movies.Suggest(prefix, limit, !canSeeUnpublished)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// lookupMovie finds a movie by its ID in an outside catalogue, such as
+// ?source=imdb&id=tt0111161.
+func (app *application) lookupMovie(writer http.ResponseWriter, request *http.Request) {
+	val := validator.New()
+	query := request.URL.Query()
+
+	source := app.readString(query, "source", "")
+	id := app.readString(query, "id", "")
+
+	if data.ValidateExternalID(val, source, id); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetByExternalID(source, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	visible, err := app.canSeeMovie(request, movie)
 	if err != nil {
 		app.serverErrorResponse(writer, request, err)
 		return
 	}
 
-	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "movies": movies}, nil)
+	if !visible {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
+
+	app.setRuntimeFormat(request, movie)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(writer, request, err)
 	}
@@ -54,7 +242,96 @@ func (app *application) showMovie(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	movie, err := app.models.Movies.Get(movieId)
+	val := validator.New()
+	query := request.URL.Query()
+
+	include := app.readCSV(query, "include", []string{})
+	fields := app.readCSV(query, "fields", []string{})
+
+	for _, value := range include {
+		val.Check(validator.PermittedValue(value, "credits"), "include", "must only contain credits")
+	}
+
+	for _, field := range fields {
+		val.Check(validator.PermittedValue(field, data.MovieFieldSafelist...), "fields", "invalid field value")
+	}
+
+	val.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+
+	locales := app.readLocales(request, val)
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetFields(movieId, fields)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.redirectMergedMovie(writer, request, movieId)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	visible, err := app.canSeeMovie(request, movie)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	if !visible {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	if err := app.models.Titles.Localize([]*data.Movie{movie}, locales); err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Vary", "Accept-Language")
+
+	if validator.PermittedValue("credits", include...) {
+		movie.Credits, err = app.models.Credits.GetAllForMovie(movie.ID)
+		if err != nil {
+			app.serverErrorResponse(writer, request, err)
+			return
+		}
+	}
+
+	// Credits and translations aren't versioned with the movie, so a response
+	// including them can't be validated against the movie's ETag.
+	if movie.Credits == nil && movie.TitleLocale == "" {
+		headers.Set("ETag", app.etag(movie))
+
+		if match := request.Header.Get("If-None-Match"); match != "" && app.matchETag(match, headers.Get("ETag"), true) {
+			writer.Header().Set("ETag", headers.Get("ETag"))
+			writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	app.setRuntimeFormat(request, movie)
+
+	envelope := map[string]any{"movie": movie}
+	if len(fields) > 0 {
+		envelope["movie"] = movie.Project(fields)
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, envelope, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// redirectMergedMovie points a client asking for a movie that has been merged into
+// another at that movie, keeping the query string. Any other missing movie is a 404.
+func (app *application) redirectMergedMovie(writer http.ResponseWriter, request *http.Request, movieId int64) {
+	targetId, err := app.models.Movies.GetRedirect(movieId)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -65,7 +342,15 @@ func (app *application) showMovie(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, nil)
+	location := fmt.Sprintf("/v1/movies/%d", targetId)
+	if request.URL.RawQuery != "" {
+		location += "?" + request.URL.RawQuery
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", location)
+
+	err = app.writeJSON(writer, http.StatusMovedPermanently, map[string]any{"message": "movie has been merged into another", "movie_id": targetId}, headers)
 	if err != nil {
 		app.serverErrorResponse(writer, request, err)
 	}
@@ -73,10 +358,11 @@ func (app *application) showMovie(writer http.ResponseWriter, request *http.Requ
 
 func (app *application) createMovie(writer http.ResponseWriter, request *http.Request) {
 	var input struct {
-		Title   string       `json:"title"`
-		Year    int32        `json:"year"`
-		Runtime data.Runtime `json:"runtime"`
-		Genres  []string     `json:"genres"`
+		Title       string           `json:"title"`
+		Year        int32            `json:"year"`
+		Runtime     data.Runtime     `json:"runtime"`
+		Genres      []string         `json:"genres"`
+		ExternalIDs data.ExternalIDs `json:"external_ids"`
 	}
 
 	err := app.readJSON(writer, request, &input)
@@ -86,26 +372,67 @@ func (app *application) createMovie(writer http.ResponseWriter, request *http.Re
 	}
 
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
-		Genres:  input.Genres,
+		Title:       input.Title,
+		Year:        input.Year,
+		Runtime:     input.Runtime,
+		Genres:      input.Genres,
+		ExternalIDs: input.ExternalIDs,
+	}
+
+	genres, err := app.models.Genres.Vocabulary()
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
 	}
 
 	val := validator.New()
-	if data.ValidateMovie(val, movie); !val.Valid() {
+	force := app.readBool(request.URL.Query(), "force", false, val)
+
+	if data.ValidateMovie(val, movie, genres); !val.Valid() {
 		app.failedValidationResponse(writer, request, val.Errors)
 		return
 	}
 
-	if err := app.models.Movies.Insert(movie); err != nil {
-		app.serverErrorResponse(writer, request, err)
+	user := app.contextGetUser(request)
+
+	// Unless forced, refuse to create what looks like a movie we already have, and
+	// list the likely matches so the client can use one of them instead.
+	if !force {
+		canSeeUnpublished, err := app.canSeeUnpublished(request)
+		if err != nil {
+			app.serverErrorResponse(writer, request, err)
+			return
+		}
+
+		candidates, err := app.models.Movies.FindDuplicates(movie, !canSeeUnpublished, user.ID)
+		if err != nil {
+			app.serverErrorResponse(writer, request, err)
+			return
+		}
+
+		if len(candidates) > 0 {
+			message := "a movie with this title and year may already exist, use force=true to create it anyway"
+			app.errorResponse(writer, request, http.StatusConflict, map[string]any{"message": message, "candidates": candidates})
+			return
+		}
+	}
+
+	if err := app.models.Movies.Insert(movie, user.ID); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			val.AddError("external_ids", "contains an id already used by another movie")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
 		return
 	}
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
 
+	app.setRuntimeFormat(request, movie)
+
 	app.writeJSON(writer, http.StatusCreated, map[string]any{"movie": movie}, nil)
 }
 
@@ -127,44 +454,91 @@ func (app *application) updateMovie(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	var input struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres"`
-	}
-
-	if err = app.readJSON(writer, request, &input); err != nil {
-		app.badRequestResponse(writer, request, err)
+	// With If-Match the client is editing a specific version; if the movie has moved on
+	// since, reject the request rather than applying the changes to the newer version.
+	ifMatch := request.Header.Get("If-Match")
+	if ifMatch != "" && !app.matchETag(ifMatch, app.etag(movie), false) {
+		app.preconditionFailedResponse(writer, request)
 		return
 	}
 
-	if input.Title != nil {
-		movie.Title = *input.Title
-	}
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json-patch+json", "application/merge-patch+json":
+		if err := app.patchMovie(writer, request, movie, mediaType); err != nil {
+			switch {
+			case errors.Is(err, jsonpatch.ErrTestFailed):
+				app.errorResponse(writer, request, http.StatusConflict, err.Error())
+			default:
+				app.badRequestResponse(writer, request, err)
+			}
+			return
+		}
 
-	if input.Year != nil {
-		movie.Year = *input.Year
-	}
+	case "", "application/json":
+		var input struct {
+			Title       *string          `json:"title"`
+			Year        *int32           `json:"year"`
+			Runtime     *data.Runtime    `json:"runtime"`
+			Genres      []string         `json:"genres"`
+			ExternalIDs data.ExternalIDs `json:"external_ids"`
+		}
+
+		if err = app.readJSON(writer, request, &input); err != nil {
+			app.badRequestResponse(writer, request, err)
+			return
+		}
+
+		if input.Title != nil {
+			movie.Title = *input.Title
+		}
+
+		if input.Year != nil {
+			movie.Year = *input.Year
+		}
+
+		if input.Runtime != nil {
+			movie.Runtime = *input.Runtime
+		}
+
+		if input.Genres != nil {
+			movie.Genres = input.Genres
+		}
 
-	if input.Runtime != nil {
-		movie.Runtime = *input.Runtime
+		// The external IDs sent replace the movie's whole set; an empty object clears it.
+		if input.ExternalIDs != nil {
+			movie.ExternalIDs = input.ExternalIDs
+		}
+
+	default:
+		app.unsupportedMediaTypeResponse(writer, request)
+		return
 	}
 
-	if input.Genres != nil {
-		movie.Genres = input.Genres
+	genres, err := app.models.Genres.Vocabulary()
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
 	}
 
 	val := validator.New()
-	if data.ValidateMovie(val, movie); !val.Valid() {
+	if data.ValidateMovie(val, movie, genres); !val.Valid() {
 		app.failedValidationResponse(writer, request, val.Errors)
 		return
 	}
 
-	if err := app.models.Movies.Update(movie); err != nil {
+	user := app.contextGetUser(request)
+
+	if err := app.models.Movies.Update(movie, user.ID); err != nil {
 		switch {
+		case errors.Is(err, data.ErrEditConflict) && ifMatch != "":
+			app.preconditionFailedResponse(writer, request)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(writer, request)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			val.AddError("external_ids", "contains an id already used by another movie")
+			app.failedValidationResponse(writer, request, val.Errors)
 		default:
 			app.serverErrorResponse(writer, request, err)
 		}
@@ -172,12 +546,89 @@ func (app *application) updateMovie(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", app.etag(movie))
+
+	app.setRuntimeFormat(request, movie)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(writer, request, err)
 	}
 }
 
+// patchMovie applies an RFC 6902 JSON Patch or RFC 7396 Merge Patch request body to
+// the editable fields of movie. The runtime is patched in the format the client asked
+// for, so that a test operation can compare it with a runtime the client was sent.
+func (app *application) patchMovie(writer http.ResponseWriter, request *http.Request, movie *data.Movie, mediaType string) error {
+	type document struct {
+		Title       string                `json:"title"`
+		Year        int32                 `json:"year"`
+		Runtime     data.FormattedRuntime `json:"runtime"`
+		Genres      []string              `json:"genres"`
+		ExternalIDs data.ExternalIDs      `json:"external_ids"`
+	}
+
+	original, err := json.Marshal(document{
+		Title:       movie.Title,
+		Year:        movie.Year,
+		Runtime:     data.FormattedRuntime{Runtime: movie.Runtime, Format: app.readRuntimeFormat(request)},
+		Genres:      movie.Genres,
+		ExternalIDs: movie.ExternalIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+
+	switch mediaType {
+	case "application/json-patch+json":
+		var operations []jsonpatch.Operation
+		if err := app.readJSON(writer, request, &operations); err != nil {
+			return err
+		}
+
+		patched, err = jsonpatch.Apply(original, operations)
+
+	case "application/merge-patch+json":
+		var patch json.RawMessage
+		if err := app.readJSON(writer, request, &patch); err != nil {
+			return err
+		}
+
+		patched, err = jsonpatch.MergePatch(original, patch)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Decode strictly so that patches touching anything other than the editable
+	// fields are rejected.
+	var result document
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&result); err != nil {
+		return app.jsonError(err)
+	}
+
+	movie.Title = result.Title
+	movie.Year = result.Year
+	movie.Runtime = result.Runtime.Runtime
+	movie.Genres = result.Genres
+	movie.ExternalIDs = result.ExternalIDs
+
+	// Removing external_ids altogether clears them rather than leaving them untouched.
+	if movie.ExternalIDs == nil {
+		movie.ExternalIDs = data.ExternalIDs{}
+	}
+
+	return nil
+}
+
 func (app *application) deleteMovie(writer http.ResponseWriter, request *http.Request) {
 	movieId, err := app.readIDParam(request)
 	if err != nil {
@@ -185,10 +636,36 @@ func (app *application) deleteMovie(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	if err := app.models.Movies.Delete(movieId); err != nil {
+	// A conditional delete only goes ahead if the movie is still at the version the
+	// client last saw.
+	var version int32
+
+	if ifMatch := request.Header.Get("If-Match"); ifMatch != "" {
+		movie, err := app.models.Movies.Get(movieId)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(writer, request)
+			default:
+				app.serverErrorResponse(writer, request, err)
+			}
+			return
+		}
+
+		if !app.matchETag(ifMatch, app.etag(movie), false) {
+			app.preconditionFailedResponse(writer, request)
+			return
+		}
+
+		version = movie.Version
+	}
+
+	if err := app.models.Movies.Delete(movieId, version); err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(writer, request)
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(writer, request)
 		default:
 			app.serverErrorResponse(writer, request, err)
 		}
@@ -200,3 +677,82 @@ func (app *application) deleteMovie(writer http.ResponseWriter, request *http.Re
 		app.serverErrorResponse(writer, request, err)
 	}
 }
+
+// mergeMovie folds a duplicate movie into the target movie and removes it, leaving its
+// ID redirecting to the target.
+func (app *application) mergeMovie(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		Into int64 `json:"into"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	val := validator.New()
+	val.Check(input.Into > 0, "into", "must be provided")
+	val.Check(input.Into != movieId, "into", "must be a different movie")
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	movies := make([]*data.Movie, 2)
+
+	for i, id := range []int64{movieId, input.Into} {
+		movies[i], err = app.models.Movies.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound) && i == 0:
+				app.notFoundResponse(writer, request)
+			case errors.Is(err, data.ErrRecordNotFound):
+				val.AddError("into", "must be an existing movie")
+				app.failedValidationResponse(writer, request, val.Errors)
+			default:
+				app.serverErrorResponse(writer, request, err)
+			}
+			return
+		}
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Movies.Merge(movies[0], movies[1], user.ID); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	// Target keeps its own poster if it had one, leaving source's unused.
+	app.deletePosters(movies[0].PosterURL, movies[0].PosterThumbnailURL)
+
+	target, err := app.models.Movies.Get(input.Into)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", app.etag(target))
+
+	app.setRuntimeFormat(request, target)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": target}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/jsonpatch"
+)
+
+// TestPatchMovieRuntimeFormat checks that a JSON Patch test operation on the runtime
+// compares it in the format the client asked for.
+func TestPatchMovieRuntimeFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		value   string
+		want    data.Runtime
+		wantErr bool
+	}{
+		{name: "default format", value: `"107 mins"`, want: 110},
+		{name: "hm format", query: "?runtime_format=hm", value: `"1h 47m"`, want: 110},
+		{name: "iso8601 format", query: "?runtime_format=iso8601", value: `"PT1H47M"`, want: 110},
+		{name: "other format", query: "?runtime_format=hm", value: `"107 mins"`, wantErr: true},
+		{name: "other runtime", query: "?runtime_format=hm", value: `"1h 48m"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newTestApplication(t)
+
+			body := `[{"op":"test","path":"/runtime","value":` + tt.value + `},{"op":"replace","path":"/runtime","value":"1h 50m"}]`
+			request := httptest.NewRequest(http.MethodPatch, "/v1/movies/7"+tt.query, strings.NewReader(body))
+
+			movie := &data.Movie{ID: 7, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation"}}
+
+			err := app.patchMovie(httptest.NewRecorder(), request, movie, "application/json-patch+json")
+			if tt.wantErr {
+				if !errors.Is(err, jsonpatch.ErrTestFailed) {
+					t.Errorf("got %v; want ErrTestFailed", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if movie.Runtime != tt.want {
+				t.Errorf("got runtime %d; want %d", movie.Runtime, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listPeople(writer http.ResponseWriter, request *http.Request) {
+	var filters data.Filters
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	name := app.readString(query, "name", "")
+
+	filters.Page = app.readInt(query, "page", 1, val)
+	filters.PageSize = app.readInt(query, "page_size", 20, val)
+	filters.Sort = app.readString(query, "sort", "name")
+	filters.SortSafelist = []string{"id", "name", "birth_year", "-id", "-name", "-birth_year"}
+
+	if data.ValidateFilters(val, filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	people, metadata, err := app.models.People.GetAll(name, filters)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "people": people}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) createPerson(writer http.ResponseWriter, request *http.Request) {
+	var input struct {
+		Name      string `json:"name"`
+		BirthYear int32  `json:"birth_year"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	person := &data.Person{
+		Name:      strings.TrimSpace(input.Name),
+		BirthYear: input.BirthYear,
+	}
+
+	val := validator.New()
+	if data.ValidatePerson(val, person); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if err := app.models.People.Insert(person); err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/people/%d", person.ID))
+
+	err := app.writeJSON(writer, http.StatusCreated, map[string]any{"person": person}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) showPerson(writer http.ResponseWriter, request *http.Request) {
+	personId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	person, err := app.models.People.Get(personId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"person": person}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) updatePerson(writer http.ResponseWriter, request *http.Request) {
+	personId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	person, err := app.models.People.Get(personId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name      *string `json:"name"`
+		BirthYear *int32  `json:"birth_year"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	if input.Name != nil {
+		person.Name = strings.TrimSpace(*input.Name)
+	}
+
+	if input.BirthYear != nil {
+		person.BirthYear = *input.BirthYear
+	}
+
+	val := validator.New()
+	if data.ValidatePerson(val, person); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if err := app.models.People.Update(person); err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"person": person}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) deletePerson(writer http.ResponseWriter, request *http.Request) {
+	personId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	if err := app.models.People.Delete(personId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"message": "person successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// listPersonMovies lists the person's filmography, newest first, optionally narrowed
+// to one role with ?role=. Each movie carries the person's credits on it, giving the
+// role and character they played.
+func (app *application) listPersonMovies(writer http.ResponseWriter, request *http.Request) {
+	personId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		data.MovieCriteria
+		data.Filters
+	}
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	person := int(personId)
+	input.Person = &person
+	input.Role = app.readString(query, "role", "")
+
+	input.Filters.Page = app.readInt(query, "page", 1, val)
+	input.Filters.PageSize = app.readInt(query, "page_size", 20, val)
+	input.Filters.Sort = app.readString(query, "sort", "-year")
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime", "rating", "-rating"}
+
+	data.ValidateMovieCriteria(val, input.MovieCriteria)
+
+	if data.ValidateFilters(val, input.Filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, err := app.models.People.Get(personId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	input.PublishedOnly = !canSeeUnpublished
+	input.Viewer = app.contextGetUser(request).ID
+
+	movies, metadata, _, err := app.models.Movies.GetAll(input.MovieCriteria, input.Filters, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	byID := make(map[int64]*data.Movie, len(movies))
+	movieIDs := make([]int64, len(movies))
+
+	for i, movie := range movies {
+		byID[movie.ID] = movie
+		movieIDs[i] = movie.ID
+	}
+
+	credits, err := app.models.Credits.GetAllForPerson(personId, movieIDs, input.Role)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	for _, credit := range credits {
+		byID[credit.MovieID].Credits = append(byID[credit.MovieID].Credits, credit)
+	}
+
+	app.setRuntimeFormat(request, movies...)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "movies": movies}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sparrowsl/greenlight/internal/data"
+)
+
+// TestListPersonMoviesCredits checks that each movie in a filmography comes with the
+// role and character the person had on it.
+func TestListPersonMoviesCredits(t *testing.T) {
+	app, mock := newTestApplication(t)
+
+	mock.ExpectQuery(`FROM people`).
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "birth_year", "version", "created_at"}).
+			AddRow(3, "Auli'i Cravalho", 2000, 1, time.Now()))
+
+	columns := []string{"count", "id", "title", "year", "runtime", "created_at", "created_by", "genres", "version",
+		"average_rating", "rating_count", "poster_url", "poster_thumbnail_url", "status", "publish_at", "external_ids",
+		"headline", "sort"}
+
+	movie := func(id int64, title string, year int) []driver.Value {
+		return []driver.Value{2, id, title, year, 107, time.Now(), 1, "{animation}", 1,
+			0.0, 0, "", "", data.StatusPublished, nil, []byte("{}"), "", year}
+	}
+
+	mock.ExpectQuery(`FROM movies`).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(movie(8, "Moana 2", 2024)...).AddRow(movie(7, "Moana", 2016)...))
+
+	mock.ExpectQuery(`FROM movie_credits`).
+		WithArgs(int64(3), sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "movie_id", "person_id", "name", "role", "character"}).
+			AddRow(1, 7, 3, "Auli'i Cravalho", "actor", "Moana").
+			AddRow(2, 8, 3, "Auli'i Cravalho", "writer", "").
+			AddRow(3, 8, 3, "Auli'i Cravalho", "actor", "Moana"))
+
+	recorder := httptest.NewRecorder()
+	app.listPersonMovies(recorder, newTestRequest(app, data.AnonymousUser, "3"))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d; want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	var response struct {
+		Movies []struct {
+			ID      int64 `json:"id"`
+			Credits []struct {
+				Role      string `json:"role"`
+				Character string `json:"character"`
+			} `json:"credits"`
+		} `json:"movies"`
+	}
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int64][]string{8: {"writer/", "actor/Moana"}, 7: {"actor/Moana"}}
+
+	if len(response.Movies) != len(want) {
+		t.Fatalf("got %d movies; want %d", len(response.Movies), len(want))
+	}
+
+	for _, movie := range response.Movies {
+		got := []string{}
+		for _, credit := range movie.Credits {
+			got = append(got, credit.Role+"/"+credit.Character)
+		}
+
+		if !slices.Equal(got, want[movie.ID]) {
+			t.Errorf("movie %d: got credits %v; want %v", movie.ID, got, want[movie.ID])
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/images"
+	"github.com/sparrowsl/greenlight/internal/storage"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+const (
+	maxPosterBytes       = 10 << 20 // 10MB
+	maxPosterDimension   = 6000     // pixels, checked before decoding to keep memory bounded
+	posterThumbnailWidth = 300
+)
+
+// uploadMoviePoster stores the image sent in the "poster" field of a multipart form
+// as the movie's poster, together with a thumbnail. Files are named by the hash of
+// their content, so they never change once stored and can be cached indefinitely.
+func (app *application) uploadMoviePoster(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(movieId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	// Leave some room over the file size for the rest of the multipart body.
+	request.Body = http.MaxBytesReader(writer, request.Body, maxPosterBytes+1<<20)
+
+	if err := request.ParseMultipartForm(maxPosterBytes); err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(writer, request, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit))
+		default:
+			app.badRequestResponse(writer, request, errors.New("body must be a multipart form"))
+		}
+		return
+	}
+
+	val := validator.New()
+
+	file, _, err := request.FormFile("poster")
+	if err != nil {
+		val.AddError("poster", "must be provided")
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxPosterBytes+1))
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	val.Check(len(content) <= maxPosterBytes, "poster", fmt.Sprintf("must not be larger than %d bytes", maxPosterBytes))
+
+	img, err := images.Sniff(content)
+	switch {
+	case errors.Is(err, images.ErrUnsupportedFormat):
+		val.AddError("poster", "must be a JPEG, PNG or WebP image")
+	case err != nil:
+		val.AddError("poster", "must be a valid image")
+	default:
+		val.Check(img.Width <= maxPosterDimension && img.Height <= maxPosterDimension, "poster",
+			fmt.Sprintf("must not be larger than %dx%d pixels", maxPosterDimension, maxPosterDimension))
+	}
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	name := "posters/" + hex.EncodeToString(sum[:])
+
+	if err := app.storage.Put(request.Context(), name+img.Extension, bytes.NewReader(content)); err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	thumbnail, err := img.Thumbnail(posterThumbnailWidth)
+	if err != nil {
+		app.deletePosters(app.storage.URL(name + img.Extension))
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	key := fmt.Sprintf("%s_w%d.jpg", name, posterThumbnailWidth)
+
+	if err := app.storage.Put(request.Context(), key, bytes.NewReader(thumbnail)); err != nil {
+		app.deletePosters(app.storage.URL(name + img.Extension))
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	replaced := []string{movie.PosterURL, movie.PosterThumbnailURL}
+
+	movie.PosterURL = app.storage.URL(name + img.Extension)
+	movie.PosterThumbnailURL = app.storage.URL(key)
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Movies.Update(movie, user.ID); err != nil {
+		// The new files are only kept if nothing else already uses them.
+		app.deletePosters(movie.PosterURL, movie.PosterThumbnailURL)
+
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	app.deletePosters(replaced...)
+
+	headers := make(http.Header)
+	headers.Set("ETag", app.etag(movie))
+
+	app.setRuntimeFormat(request, movie)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// deletePosters removes the stored poster and thumbnail files at the given URLs that
+// no movie uses any more. Failures are only logged, since a leftover file does no harm.
+func (app *application) deletePosters(urls ...string) {
+	unused, err := app.models.Movies.UnusedPosters(urls)
+	if err != nil {
+		app.logger.Println(err)
+		return
+	}
+
+	for _, url := range unused {
+		key, ok := app.storage.Key(url)
+		if !ok {
+			continue
+		}
+
+		if err := app.storage.Delete(context.Background(), key); err != nil {
+			app.logger.Println(err)
+		}
+	}
+}
+
+// showImage serves a stored image. Stored files never change, so they are marked as
+// cacheable for a year.
+func (app *application) showImage(writer http.ResponseWriter, request *http.Request) {
+	key := chi.URLParam(request, "*")
+
+	file, err := app.storage.Open(request.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	writer.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	writer.Header().Set("ETag", fmt.Sprintf("%q", key))
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(writer, request, key, file.ModTime(), file)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+// canSeeUnpublished reports whether the user may see movies that aren't published.
+// Editors and publishers can; readers holding only movies:read can't.
+func (app *application) canSeeUnpublished(request *http.Request) (bool, error) {
+	user := app.contextGetUser(request)
+	if user.IsAnonymous() {
+		return false, nil
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include("movies:write") || permissions.Include("movies:publish"), nil
+}
+
+// canSeeMovie reports whether the user may see the movie. Readers who can't are
+// answered as if it didn't exist. Owners can always see their own movies.
+func (app *application) canSeeMovie(request *http.Request, movie *data.Movie) (bool, error) {
+	if movie.Status == data.StatusPublished || movie.CreatedBy != 0 && movie.CreatedBy == app.contextGetUser(request).ID {
+		return true, nil
+	}
+
+	return app.canSeeUnpublished(request)
+}
+
+// readVisibleMovie loads the movie with the given ID, answering 404 if it doesn't
+// exist, is in the trash or the user can't see it. It writes the error response itself
+// and returns false on failure.
+func (app *application) readVisibleMovie(writer http.ResponseWriter, request *http.Request, movieId int64) (*data.Movie, bool) {
+	movie, err := app.models.Movies.Get(movieId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return nil, false
+	}
+
+	visible, err := app.canSeeMovie(request, movie)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return nil, false
+	}
+
+	if !visible {
+		app.notFoundResponse(writer, request)
+		return nil, false
+	}
+
+	return movie, true
+}
+
+// updateMovieStatus moves a movie through the publication workflow. Editors holding
+// movies:write, or movies:write:own for their own movies, can submit drafts for review
+// and send them back; making a movie go live, scheduling it, or archiving it also takes
+// movies:publish.
+func (app *application) updateMovieStatus(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		Status    string     `json:"status"`
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(movieId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	ifMatch := request.Header.Get("If-Match")
+	if ifMatch != "" && !app.matchETag(ifMatch, app.etag(movie), false) {
+		app.preconditionFailedResponse(writer, request)
+		return
+	}
+
+	val := validator.New()
+	if data.ValidateStatusChange(val, movie.Status, input.Status, input.PublishAt); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if data.RequiresPublishPermission(movie.Status, input.Status, input.PublishAt) {
+		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		if err != nil {
+			app.serverErrorResponse(writer, request, err)
+			return
+		}
+
+		if !permissions.Include("movies:publish") {
+			app.notPermittedResponse(writer, request)
+			return
+		}
+	}
+
+	movie.Status = input.Status
+	movie.PublishAt = input.PublishAt
+
+	if err := app.models.Movies.SetStatus(movie, user.ID); err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict) && ifMatch != "":
+			app.preconditionFailedResponse(writer, request)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", app.etag(movie))
+
+	app.setRuntimeFormat(request, movie)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// publishScheduled periodically publishes the movies in review whose publish_at time
+// has come.
+func (app *application) publishScheduled() {
+	app.every(app.config.publishing.interval, func() {
+		published, err := app.models.Movies.PublishDue(time.Now())
+		if err != nil {
+			app.logger.Println(err)
+		} else if published > 0 {
+			app.logger.Printf("published %d scheduled movies", published)
+		}
+	})
+}
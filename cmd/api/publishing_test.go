@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/sparrowsl/greenlight/internal/data"
+)
+
+// newTestApplication returns an application backed by a mock database.
+func newTestApplication(t *testing.T) (*application, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	app := &application{
+		logger: log.New(io.Discard, "", 0),
+		models: data.NewModel(db),
+	}
+
+	return app, mock
+}
+
+// newTestRequest returns a GET request made by user for the movie with the given ID.
+func newTestRequest(app *application, user *data.User, movieId string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", movieId)
+	request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeContext))
+
+	return app.contextSetUser(request, user)
+}
+
+// expectMovie makes the mock database return a movie owned by createdBy in the given
+// status when it is looked up by ID.
+func expectMovie(mock sqlmock.Sqlmock, id int64, status string, createdBy int64) {
+	columns := []string{"id", "title", "year", "runtime", "created_at", "created_by", "genres", "version",
+		"average_rating", "rating_count", "poster_url", "poster_thumbnail_url", "status", "publish_at", "external_ids"}
+
+	values := []driver.Value{id, "Moana", 2016, 107, time.Now(), createdBy, "{animation}", 1,
+		0.0, 0, "", "", status, nil, []byte("{}")}
+
+	mock.ExpectQuery(`FROM movies\s+WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(values...))
+}
+
+func expectPermissions(mock sqlmock.Sqlmock, userID int64, codes ...string) {
+	rows := sqlmock.NewRows([]string{"code"})
+	for _, code := range codes {
+		rows.AddRow(code)
+	}
+
+	mock.ExpectQuery(`FROM permissions`).WithArgs(userID).WillReturnRows(rows)
+}
+
+// TestMovieSubresourceVisibility checks that the endpoints listing what belongs to a
+// movie answer 404 for a movie the user isn't allowed to see, just as showing the
+// movie itself does.
+func TestMovieSubresourceVisibility(t *testing.T) {
+	handlers := []struct {
+		name    string
+		handler func(app *application) http.HandlerFunc
+		query   string
+	}{
+		{"credits", func(app *application) http.HandlerFunc { return app.listMovieCredits }, `FROM movie_credits`},
+		{"titles", func(app *application) http.HandlerFunc { return app.listMovieTitles }, `FROM movie_titles`},
+		{"reviews", func(app *application) http.HandlerFunc { return app.listMovieReviews }, `FROM reviews`},
+		{"revisions", func(app *application) http.HandlerFunc { return app.listMovieRevisions }, `FROM movie_revisions`},
+	}
+
+	const ownerID = 1
+
+	reader := &data.User{ID: 2, Activated: true}
+	editor := &data.User{ID: 3, Activated: true}
+	owner := &data.User{ID: ownerID, Activated: true}
+
+	tests := []struct {
+		name        string
+		status      string
+		user        *data.User
+		permissions []string // nil when the permissions shouldn't need checking
+		want        int
+	}{
+		{name: "published to reader", status: data.StatusPublished, user: reader, want: http.StatusOK},
+		{name: "published to anonymous", status: data.StatusPublished, user: data.AnonymousUser, want: http.StatusOK},
+		{name: "draft to owner", status: data.StatusDraft, user: owner, want: http.StatusOK},
+		{name: "draft to editor", status: data.StatusDraft, user: editor, permissions: []string{"movies:read", "movies:write"}, want: http.StatusOK},
+		{name: "draft to publisher", status: data.StatusDraft, user: editor, permissions: []string{"movies:read", "movies:publish"}, want: http.StatusOK},
+		{name: "draft to reader", status: data.StatusDraft, user: reader, permissions: []string{"movies:read"}, want: http.StatusNotFound},
+		{name: "scheduled to reader", status: data.StatusInReview, user: reader, permissions: []string{"movies:read"}, want: http.StatusNotFound},
+		{name: "archived to reader", status: data.StatusArchived, user: reader, permissions: []string{"movies:read"}, want: http.StatusNotFound},
+		{name: "draft to anonymous", status: data.StatusDraft, user: data.AnonymousUser, want: http.StatusNotFound},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+"/"+tt.name, func(t *testing.T) {
+				app, mock := newTestApplication(t)
+
+				expectMovie(mock, 7, tt.status, ownerID)
+				if tt.permissions != nil {
+					expectPermissions(mock, tt.user.ID, tt.permissions...)
+				}
+				if tt.want == http.StatusOK {
+					mock.ExpectQuery(h.query).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				}
+
+				recorder := httptest.NewRecorder()
+				h.handler(app)(recorder, newTestRequest(app, tt.user, "7"))
+
+				if recorder.Code != tt.want {
+					t.Errorf("got status %d; want %d: %s", recorder.Code, tt.want, recorder.Body)
+				}
+
+				if err := mock.ExpectationsWereMet(); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
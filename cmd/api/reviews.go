@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listMovieReviews(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var filters data.Filters
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	filters.Page = app.readInt(query, "page", 1, val)
+	filters.PageSize = app.readInt(query, "page_size", 20, val)
+	filters.Sort = app.readString(query, "sort", "-created_at")
+	filters.SortSafelist = []string{"created_at", "-created_at", "rating", "-rating"}
+
+	if data.ValidateFilters(val, filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, ok := app.readVisibleMovie(writer, request, movieId); !ok {
+		return
+	}
+
+	reviews, metadata, err := app.models.Reviews.GetAllForMovie(movieId, filters)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "reviews": reviews}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) createMovieReview(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		Rating int    `json:"rating"`
+		Body   string `json:"body"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	review := &data.Review{
+		MovieID: movieId,
+		UserID:  user.ID,
+		Rating:  input.Rating,
+		Body:    input.Body,
+	}
+
+	val := validator.New()
+	if data.ValidateReview(val, review); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, ok := app.readVisibleMovie(writer, request, movieId); !ok {
+		return
+	}
+
+	if err := app.models.Reviews.Insert(review); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		case errors.Is(err, data.ErrDuplicateReview):
+			val.AddError("movie", "you have already reviewed this movie")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/movies/%d/reviews/%d", movieId, review.ID))
+
+	err = app.writeJSON(writer, http.StatusCreated, map[string]any{"review": review}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) updateMovieReview(writer http.ResponseWriter, request *http.Request) {
+	review, ok := app.readOwnReview(writer, request)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Rating *int    `json:"rating"`
+		Body   *string `json:"body"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	if input.Rating != nil {
+		review.Rating = *input.Rating
+	}
+
+	if input.Body != nil {
+		review.Body = *input.Body
+	}
+
+	val := validator.New()
+	if data.ValidateReview(val, review); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if err := app.models.Reviews.Update(review); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err := app.writeJSON(writer, http.StatusOK, map[string]any{"review": review}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) deleteMovieReview(writer http.ResponseWriter, request *http.Request) {
+	review, ok := app.readOwnReview(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := app.models.Reviews.Delete(review); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err := app.writeJSON(writer, http.StatusOK, map[string]any{"message": "review successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// readOwnReview loads the review named in the URL, checking that it belongs to the
+// current user. It writes the error response itself and returns false on failure.
+func (app *application) readOwnReview(writer http.ResponseWriter, request *http.Request) (*data.Review, bool) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return nil, false
+	}
+
+	reviewId, err := app.readIntParam(request, "review_id")
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return nil, false
+	}
+
+	review, err := app.models.Reviews.Get(movieId, reviewId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return nil, false
+	}
+
+	if review.UserID != app.contextGetUser(request).ID {
+		app.notPermittedResponse(writer, request)
+		return nil, false
+	}
+
+	return review, true
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listMovieRevisions(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var filters data.Filters
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	filters.Page = app.readInt(query, "page", 1, val)
+	filters.PageSize = app.readInt(query, "page_size", 20, val)
+	filters.Sort = app.readString(query, "sort", "-version")
+	filters.SortSafelist = []string{"version", "-version"}
+
+	if data.ValidateFilters(val, filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, ok := app.readVisibleMovie(writer, request, movieId); !ok {
+		return
+	}
+
+	revisions, metadata, err := app.models.Revisions.GetAllForMovie(movieId, filters)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	format := app.readRuntimeFormat(request)
+	for _, revision := range revisions {
+		revision.RuntimeFormat = format
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "revisions": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) diffMovieRevisions(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	from := app.readInt(query, "from", 0, val)
+	to := app.readInt(query, "to", 0, val)
+
+	val.Check(from > 0, "from", "must be a positive version number")
+	val.Check(to > 0, "to", "must be a positive version number")
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, ok := app.readVisibleMovie(writer, request, movieId); !ok {
+		return
+	}
+
+	revisions := make([]*data.MovieRevision, 2)
+
+	for i, version := range []int{from, to} {
+		revisions[i], err = app.models.Revisions.Get(movieId, int32(version))
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(writer, request)
+			default:
+				app.serverErrorResponse(writer, request, err)
+			}
+			return
+		}
+	}
+
+	format := app.readRuntimeFormat(request)
+	revisions[0].RuntimeFormat, revisions[1].RuntimeFormat = format, format
+
+	changes := data.DiffRevisions(revisions[0], revisions[1])
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"from": from, "to": to, "changes": changes}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// revertMovie restores the fields of an earlier revision. The revert is saved as a
+// new version, so it can itself be reverted.
+func (app *application) revertMovie(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	version, err := app.readIntParam(request, "version")
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(movieId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	revision, err := app.models.Revisions.Get(movieId, int32(version))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	movie.Title = revision.Title
+	movie.Year = revision.Year
+	movie.Runtime = revision.Runtime
+	movie.Genres = revision.Genres
+
+	genres, err := app.models.Genres.Vocabulary()
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	val := validator.New()
+	if data.ValidateMovie(val, movie, genres); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Movies.Update(movie, user.ID); err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	app.setRuntimeFormat(request, movie)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
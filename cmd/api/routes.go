@@ -17,6 +17,7 @@ func (app *application) routes() http.Handler {
 	router.Use(app.enableCORS)
 	router.Use(app.rateLimit)
 	router.Use(app.authenticate)
+	router.Use(app.runtimeFormat)
 
 	router.NotFound(app.notFoundResponse)
 	router.MethodNotAllowed(app.methodNotAllowedResponse)
@@ -27,13 +28,67 @@ func (app *application) routes() http.Handler {
 	router.Group(func(r chi.Router) {
 		r.Use(app.requireActivatedUser)
 
-		r.Post("/v1/movies", app.requirePermission("movies:write", app.createMovie))
+		r.Post("/v1/movies", app.requireOwnPermission("movies:write", app.creatorOwns, app.createMovie))
+		r.Post("/v1/movies/bulk", app.requireOwnPermission("movies:write", app.creatorOwns, app.bulkCreateMovies))
 		r.Get("/v1/movies", app.requirePermission("movies:read", app.listAllMovies))
+		r.Get("/v1/movies/export", app.requirePermission("movies:export", app.exportMovies))
+		r.Get("/v1/movies/suggest", app.requirePermission("movies:read", app.suggestMovies))
+		r.Get("/v1/movies/lookup", app.requirePermission("movies:read", app.lookupMovie))
+		r.Get("/v1/movies/trash", app.requireOwnPermission("movies:write", app.creatorOwns, app.listTrashedMovies))
+		r.Delete("/v1/movies/trash/{id}", app.requirePermission("movies:admin", app.purgeMovie))
 		r.Get("/v1/movies/{id}", app.requirePermission("movies:read", app.showMovie))
-		r.Patch("/v1/movies/{id}", app.requirePermission("movies:write", app.updateMovie))
-		r.Delete("/v1/movies/{id}", app.requirePermission("movies:write", app.deleteMovie))
+		r.Patch("/v1/movies/{id}", app.requireOwnPermission("movies:write", app.movieOwner, app.updateMovie))
+		r.Delete("/v1/movies/{id}", app.requireOwnPermission("movies:write", app.movieOwner, app.deleteMovie))
+		r.Post("/v1/movies/{id}/restore", app.requireOwnPermission("movies:write", app.movieOwner, app.restoreMovie))
+		r.Post("/v1/movies/{id}/merge", app.requirePermission("movies:admin", app.mergeMovie))
+		r.Put("/v1/movies/{id}/status", app.requireOwnPermission("movies:write", app.movieOwner, app.updateMovieStatus))
+		r.Get("/v1/movies/{id}/similar", app.requirePermission("movies:read", app.listSimilarMovies))
+		r.Put("/v1/movies/{id}/poster", app.requireOwnPermission("movies:write", app.movieOwner, app.uploadMoviePoster))
+
+		r.Get("/v1/movies/{id}/revisions", app.requirePermission("movies:read", app.listMovieRevisions))
+		r.Get("/v1/movies/{id}/revisions/diff", app.requirePermission("movies:read", app.diffMovieRevisions))
+		r.Post("/v1/movies/{id}/revisions/{version}/revert", app.requireOwnPermission("movies:write", app.movieOwner, app.revertMovie))
+
+		r.Get("/v1/movies/{id}/reviews", app.requirePermission("movies:read", app.listMovieReviews))
+		r.Post("/v1/movies/{id}/reviews", app.requirePermission("reviews:write", app.createMovieReview))
+		r.Patch("/v1/movies/{id}/reviews/{review_id}", app.requirePermission("reviews:write", app.updateMovieReview))
+		r.Delete("/v1/movies/{id}/reviews/{review_id}", app.requirePermission("reviews:write", app.deleteMovieReview))
+
+		r.Get("/v1/movies/{id}/titles", app.requirePermission("movies:read", app.listMovieTitles))
+		r.Post("/v1/movies/{id}/titles", app.requireOwnPermission("movies:write", app.movieOwner, app.createMovieTitle))
+		r.Patch("/v1/movies/{id}/titles/{title_id}", app.requireOwnPermission("movies:write", app.movieOwner, app.updateMovieTitle))
+		r.Delete("/v1/movies/{id}/titles/{title_id}", app.requireOwnPermission("movies:write", app.movieOwner, app.deleteMovieTitle))
+
+		r.Get("/v1/movies/{id}/credits", app.requirePermission("movies:read", app.listMovieCredits))
+		r.Post("/v1/movies/{id}/credits", app.requireOwnPermission("movies:write", app.movieOwner, app.createMovieCredit))
+		r.Delete("/v1/movies/{id}/credits/{credit_id}", app.requireOwnPermission("movies:write", app.movieOwner, app.deleteMovieCredit))
+
+		r.Get("/v1/people", app.requirePermission("movies:read", app.listPeople))
+		r.Post("/v1/people", app.requirePermission("movies:write", app.createPerson))
+		r.Get("/v1/people/{id}", app.requirePermission("movies:read", app.showPerson))
+		r.Patch("/v1/people/{id}", app.requirePermission("movies:write", app.updatePerson))
+		r.Delete("/v1/people/{id}", app.requirePermission("movies:write", app.deletePerson))
+		r.Get("/v1/people/{id}/movies", app.requirePermission("movies:read", app.listPersonMovies))
+
+		r.Get("/v1/users/me/watchlist", app.requirePermission("movies:read", app.listWatchlist))
+		r.Post("/v1/users/me/watchlist", app.requirePermission("movies:read", app.addToWatchlist))
+		r.Put("/v1/users/me/watchlist/{id}", app.requirePermission("movies:read", app.moveWatchlistItem))
+		r.Delete("/v1/users/me/watchlist/{id}", app.requirePermission("movies:read", app.removeFromWatchlist))
+
+		r.Get("/v1/users/me/diary", app.requirePermission("movies:read", app.listDiary))
+		r.Post("/v1/users/me/diary", app.requirePermission("movies:read", app.createDiaryEntry))
+		r.Get("/v1/users/me/diary/stats", app.requirePermission("movies:read", app.showDiaryStats))
+		r.Delete("/v1/users/me/diary/{id}", app.requirePermission("movies:read", app.deleteDiaryEntry))
+
+		r.Get("/v1/genres", app.requirePermission("movies:read", app.listGenres))
+		r.Post("/v1/genres", app.requirePermission("movies:admin", app.createGenre))
+		r.Patch("/v1/genres/{id}", app.requirePermission("movies:admin", app.updateGenre))
+		r.Post("/v1/genres/{id}/merge", app.requirePermission("movies:admin", app.mergeGenre))
 	})
 
+	// Images are public so that clients can load them straight into <img> tags.
+	router.Get("/v1/images/*", app.showImage)
+
 	router.Put("/v1/users/activated", app.activateUser)
 	router.Post("/v1/users", app.registerUser)
 	router.Get("/v1/users", app.getAllUsers)
@@ -42,6 +42,9 @@ func (app *application) serve() error {
 			"addr": server.Addr,
 		})
 
+		// Stop the periodic tasks so that waiting for the background tasks ends.
+		close(app.shutdown)
+
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
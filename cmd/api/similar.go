@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listSimilarMovies(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var filters data.Filters
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	filters.Page = app.readInt(query, "page", 1, val)
+	filters.PageSize = app.readInt(query, "page_size", 10, val)
+
+	if data.ValidatePagination(val, filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, ok := app.readVisibleMovie(writer, request, movieId); !ok {
+		return
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	movies, metadata, err := app.models.Movies.GetSimilar(movieId, app.config.similarity, filters, !canSeeUnpublished, user.ID)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	for _, similar := range movies {
+		app.setRuntimeFormat(request, similar.Movie)
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "movies": movies}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sparrowsl/greenlight/internal/data"
+)
+
+// TestListSimilarMoviesVisibility checks that similar movies are only listed for a
+// movie the user can see, and that the ranking is told who is asking so that their own
+// drafts can be among the results.
+func TestListSimilarMoviesVisibility(t *testing.T) {
+	const ownerID = 1
+
+	tests := []struct {
+		name          string
+		status        string
+		user          *data.User
+		permissions   []string
+		publishedOnly bool
+		want          int
+	}{
+		{name: "published to anonymous", status: data.StatusPublished, user: data.AnonymousUser, publishedOnly: true, want: http.StatusOK},
+		{name: "published to reader", status: data.StatusPublished, user: &data.User{ID: 2, Activated: true}, permissions: []string{"movies:read"}, publishedOnly: true, want: http.StatusOK},
+		{name: "draft to owner", status: data.StatusDraft, user: &data.User{ID: ownerID, Activated: true}, permissions: []string{"movies:read"}, publishedOnly: true, want: http.StatusOK},
+		{name: "draft to editor", status: data.StatusDraft, user: &data.User{ID: 3, Activated: true}, permissions: []string{"movies:read", "movies:write"}, want: http.StatusOK},
+		{name: "draft to reader", status: data.StatusDraft, user: &data.User{ID: 2, Activated: true}, permissions: []string{"movies:read"}, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mock := newTestApplication(t)
+
+			expectMovie(mock, 7, tt.status, ownerID)
+
+			// Readers are checked once for an unpublished movie, and once more for
+			// the listing itself.
+			if tt.status != data.StatusPublished && tt.user.ID != ownerID {
+				expectPermissions(mock, tt.user.ID, tt.permissions...)
+			}
+
+			if tt.want == http.StatusOK {
+				if !tt.user.IsAnonymous() {
+					expectPermissions(mock, tt.user.ID, tt.permissions...)
+				}
+
+				mock.ExpectQuery(`FROM movies, target`).
+					WithArgs(int64(7), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 10, 0, tt.publishedOnly, tt.user.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}))
+			}
+
+			recorder := httptest.NewRecorder()
+			app.listSimilarMovies(recorder, newTestRequest(app, tt.user, "7"))
+
+			if recorder.Code != tt.want {
+				t.Errorf("got status %d; want %d: %s", recorder.Code, tt.want, recorder.Body)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
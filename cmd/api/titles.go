@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listMovieTitles(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	if _, ok := app.readVisibleMovie(writer, request, movieId); !ok {
+		return
+	}
+
+	titles, err := app.models.Titles.GetAllForMovie(movieId)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"titles": titles}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) createMovieTitle(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		Kind   string `json:"kind"`
+		Locale string `json:"locale"`
+		Title  string `json:"title"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	title := &data.MovieTitle{
+		MovieID: movieId,
+		Kind:    input.Kind,
+		Locale:  strings.ToLower(input.Locale),
+		Title:   strings.TrimSpace(input.Title),
+	}
+
+	if title.Kind == "" {
+		title.Kind = data.TitleTranslation
+	}
+
+	val := validator.New()
+	if data.ValidateMovieTitle(val, title); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if _, err := app.models.Movies.Get(movieId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	if err := app.models.Titles.Insert(title); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateTranslation):
+			val.AddError("locale", "already has a translation for this movie")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/movies/%d/titles/%d", movieId, title.ID))
+
+	err = app.writeJSON(writer, http.StatusCreated, map[string]any{"title": title}, headers)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) updateMovieTitle(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	titleId, err := app.readIntParam(request, "title_id")
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	title, err := app.models.Titles.Get(movieId, titleId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	var input struct {
+		Kind   *string `json:"kind"`
+		Locale *string `json:"locale"`
+		Title  *string `json:"title"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	if input.Kind != nil {
+		title.Kind = *input.Kind
+	}
+
+	if input.Locale != nil {
+		title.Locale = strings.ToLower(*input.Locale)
+	}
+
+	if input.Title != nil {
+		title.Title = strings.TrimSpace(*input.Title)
+	}
+
+	val := validator.New()
+	if data.ValidateMovieTitle(val, title); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	if err := app.models.Titles.Update(title); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		case errors.Is(err, data.ErrDuplicateTranslation):
+			val.AddError("locale", "already has a translation for this movie")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"title": title}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) deleteMovieTitle(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	titleId, err := app.readIntParam(request, "title_id")
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	if err := app.models.Titles.Delete(movieId, titleId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"message": "title successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
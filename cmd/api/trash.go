@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+// listTrashedMovies lists the movies in the trash. Users holding only movies:write:own
+// see just their own.
+func (app *application) listTrashedMovies(writer http.ResponseWriter, request *http.Request) {
+	var filters data.Filters
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	filters.Page = app.readInt(query, "page", 1, val)
+	filters.PageSize = app.readInt(query, "page_size", 20, val)
+	filters.Sort = app.readString(query, "sort", "-deleted_at")
+	filters.SortSafelist = []string{"deleted_at", "-deleted_at", "title", "-title"}
+
+	if data.ValidateFilters(val, filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	ownOnly, err := app.ownOnly(request, "movies:write")
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	var createdBy int64
+	if ownOnly {
+		createdBy = app.contextGetUser(request).ID
+	}
+
+	movies, metadata, err := app.models.Movies.GetTrash(filters, createdBy)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	app.setRuntimeFormat(request, movies...)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "movies": movies}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) restoreMovie(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	if err := app.models.Movies.Restore(movieId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	movie, err := app.models.Movies.Get(movieId)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	app.setRuntimeFormat(request, movie)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) purgeMovie(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	posters, err := app.models.Movies.Purge(movieId)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	app.deletePosters(posters...)
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"message": "movie permanently deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+// purgeTrash periodically removes movies that have been in the trash for longer than
+// the configured retention period. A zero retention keeps trashed movies forever.
+func (app *application) purgeTrash() {
+	if app.config.trash.retention <= 0 {
+		return
+	}
+
+	app.every(app.config.trash.purgeInterval, func() {
+		removed, posters, err := app.models.Movies.PurgeDeletedBefore(time.Now().Add(-app.config.trash.retention))
+		if err != nil {
+			app.logger.Println(err)
+		} else if removed > 0 {
+			app.logger.Printf("purged %d movies from the trash", removed)
+			app.deletePosters(posters...)
+		}
+	})
+}
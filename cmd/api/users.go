@@ -53,7 +53,7 @@ func (app *application) registerUser(writer http.ResponseWriter, request *http.R
 		return
 	}
 
-	if err := app.models.Permissions.AddForUser(user.ID, "movies:read"); err != nil {
+	if err := app.models.Permissions.AddForUser(user.ID, "movies:read", "reviews:write"); err != nil {
 		app.serverErrorResponse(writer, request, err)
 		return
 	}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sparrowsl/greenlight/internal/data"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func (app *application) listWatchlist(writer http.ResponseWriter, request *http.Request) {
+	var filters data.Filters
+
+	val := validator.New()
+	query := request.URL.Query()
+
+	filters.Page = app.readInt(query, "page", 1, val)
+	filters.PageSize = app.readInt(query, "page_size", 20, val)
+
+	if data.ValidatePagination(val, filters); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	canSeeUnpublished, err := app.canSeeUnpublished(request)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	items, metadata, err := app.models.Watchlists.GetAllForUser(user.ID, filters, !canSeeUnpublished)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	for _, item := range items {
+		app.setRuntimeFormat(request, item.Movie)
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"metadata": metadata, "watchlist": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) addToWatchlist(writer http.ResponseWriter, request *http.Request) {
+	var input struct {
+		MovieID  int64 `json:"movie_id"`
+		Position int   `json:"position"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	val := validator.New()
+	val.Check(input.MovieID > 0, "movie_id", "must be provided")
+	val.Check(input.Position >= 0, "position", "must not be negative")
+
+	if !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(input.MovieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("movie_id", "must be an existing movie")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	visible, err := app.canSeeMovie(request, movie)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+		return
+	}
+
+	if val.Check(visible, "movie_id", "must be an existing movie"); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Watchlists.Add(user.ID, input.MovieID, input.Position); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateWatchlistItem):
+			val.AddError("movie_id", "is already on your watchlist")
+			app.failedValidationResponse(writer, request, val.Errors)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusCreated, map[string]any{"message": "movie added to watchlist"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) moveWatchlistItem(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	var input struct {
+		Position int `json:"position"`
+	}
+
+	if err := app.readJSON(writer, request, &input); err != nil {
+		app.badRequestResponse(writer, request, err)
+		return
+	}
+
+	val := validator.New()
+	if val.Check(input.Position > 0, "position", "must be greater than zero"); !val.Valid() {
+		app.failedValidationResponse(writer, request, val.Errors)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Watchlists.Move(user.ID, movieId, input.Position); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"message": "watchlist successfully reordered"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
+
+func (app *application) removeFromWatchlist(writer http.ResponseWriter, request *http.Request) {
+	movieId, err := app.readIDParam(request)
+	if err != nil {
+		app.notFoundResponse(writer, request)
+		return
+	}
+
+	user := app.contextGetUser(request)
+
+	if err := app.models.Watchlists.Remove(user.ID, movieId); err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(writer, request)
+		default:
+			app.serverErrorResponse(writer, request, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(writer, http.StatusOK, map[string]any{"message": "movie removed from watchlist"}, nil)
+	if err != nil {
+		app.serverErrorResponse(writer, request, err)
+	}
+}
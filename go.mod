@@ -13,3 +13,7 @@ require golang.org/x/time v0.5.0
 require golang.org/x/crypto v0.22.0
 
 require github.com/wneessen/go-mail v0.4.2
+
+require golang.org/x/image v0.18.0
+
+require github.com/DATA-DOG/go-sqlmock v1.5.2
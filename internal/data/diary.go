@@ -0,0 +1,219 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+// DiaryEntry records one viewing of a movie.
+type DiaryEntry struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movie_id"`
+	WatchedOn string    `json:"watched_on"` // YYYY-MM-DD
+	Rating    *int      `json:"rating,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Movie     *Movie    `json:"movie,omitempty"`
+}
+
+// DiaryStats summarises a user's viewings over one year.
+type DiaryStats struct {
+	Year         int          `json:"year"`
+	Count        int          `json:"count"`
+	TotalRuntime Runtime      `json:"total_runtime"`
+	TopGenres    []GenreCount `json:"top_genres"`
+
+	// RuntimeFormat is the format TotalRuntime is written in, "<n> mins" when empty.
+	RuntimeFormat RuntimeFormat `json:"-"`
+}
+
+func (stats DiaryStats) MarshalJSON() ([]byte, error) {
+	type plainStats DiaryStats
+
+	return json.Marshal(struct {
+		plainStats
+		TotalRuntime FormattedRuntime `json:"total_runtime"`
+	}{plainStats(stats), FormattedRuntime{stats.TotalRuntime, stats.RuntimeFormat}})
+}
+
+type GenreCount struct {
+	Genre string `json:"genre"`
+	Count int    `json:"count"`
+}
+
+func ValidateDiaryEntry(val *validator.Validator, entry *DiaryEntry) {
+	val.Check(entry.MovieID > 0, "movie_id", "must be provided")
+
+	watchedOn, err := time.Parse(time.DateOnly, entry.WatchedOn)
+
+	val.Check(entry.WatchedOn != "", "watched_on", "must be provided")
+	val.Check(err == nil, "watched_on", "must be a YYYY-MM-DD date")
+	val.Check(err != nil || !watchedOn.After(time.Now()), "watched_on", "must not be in the future")
+
+	if entry.Rating != nil {
+		val.Check(*entry.Rating >= 1, "rating", "must be at least 1")
+		val.Check(*entry.Rating <= 10, "rating", "must be at most 10")
+	}
+
+	val.Check(len(entry.Notes) <= 10_000, "notes", "must not be more than 10000 bytes long")
+}
+
+// ValidateDiaryYear checks a year that diary entries are filtered or summarised by.
+// Entries can't be dated in the future, so neither can the year.
+func ValidateDiaryYear(val *validator.Validator, year int) {
+	val.Check(year >= 1888, "year", "must be greater than 1888")
+	val.Check(year <= time.Now().Year(), "year", "must not be in the future")
+}
+
+type DiaryModel struct {
+	DB *sql.DB
+}
+
+func (m *DiaryModel) Insert(userID int64, entry *DiaryEntry) error {
+	statement := `INSERT INTO diary_entries (user_id, movie_id, watched_on, rating, notes)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	row := m.DB.QueryRowContext(ctx, statement, userID, entry.MovieID, entry.WatchedOn, entry.Rating, entry.Notes)
+	return row.Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// GetAllForUser lists the user's diary, most recent viewing first. A non-zero year
+// only lists viewings from that year. Viewings of movies in the trash are left out, as
+// are, with publishedOnly, those of movies that aren't published, unless the user
+// created them.
+func (m *DiaryModel) GetAllForUser(userID int64, year int, filters Filters, publishedOnly bool) ([]*DiaryEntry, Metadata, error) {
+	statement := `SELECT count(*) OVER(), diary_entries.id, diary_entries.movie_id, diary_entries.watched_on::text,
+					diary_entries.rating, diary_entries.notes, diary_entries.created_at, ` + movieColumns + `
+				FROM diary_entries
+				INNER JOIN movies ON movies.id = diary_entries.movie_id
+				WHERE diary_entries.user_id = $1 AND movies.deleted_at IS NULL
+				AND ($2 = 0 OR (diary_entries.watched_on >= make_date($2, 1, 1) AND diary_entries.watched_on < make_date($2 + 1, 1, 1)))
+				AND (movies.status = 'published' OR movies.created_by = $1 OR NOT $5::boolean)
+				ORDER BY diary_entries.watched_on DESC, diary_entries.id DESC
+				LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID, year, filters.limit(), filters.offset(), publishedOnly)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*DiaryEntry{}
+
+	for rows.Next() {
+		entry := DiaryEntry{Movie: &Movie{}}
+
+		dest := []any{&totalRecords, &entry.ID, &entry.MovieID, &entry.WatchedOn, &entry.Rating, &entry.Notes, &entry.CreatedAt}
+		dest = append(dest, movieFields(entry.Movie)...)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+func (m *DiaryModel) Delete(userID int64, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	statement := `DELETE FROM diary_entries
+				WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, statement, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Stats summarises the user's viewings in the given year: how many, their combined
+// runtime and the most watched genres. Like the diary itself, it leaves out movies in
+// the trash and, when publishedOnly is set, movies that aren't published and aren't
+// the user's own.
+func (m *DiaryModel) Stats(userID int64, year int, topGenres int, publishedOnly bool) (*DiaryStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	stats := &DiaryStats{Year: year, TopGenres: []GenreCount{}}
+
+	statement := `SELECT count(*), COALESCE(sum(movies.runtime), 0)
+				FROM diary_entries
+				INNER JOIN movies ON movies.id = diary_entries.movie_id
+				WHERE diary_entries.user_id = $1 AND movies.deleted_at IS NULL
+				AND diary_entries.watched_on >= make_date($2, 1, 1) AND diary_entries.watched_on < make_date($2 + 1, 1, 1)
+				AND (movies.status = 'published' OR movies.created_by = $1 OR NOT $3::boolean)`
+
+	err := m.DB.QueryRowContext(ctx, statement, userID, year, publishedOnly).Scan(&stats.Count, &stats.TotalRuntime)
+	if err != nil {
+		return nil, err
+	}
+
+	statement = `SELECT genre, count(*)
+				FROM diary_entries
+				INNER JOIN movies ON movies.id = diary_entries.movie_id
+				CROSS JOIN unnest(movies.genres) AS genre
+				WHERE diary_entries.user_id = $1 AND movies.deleted_at IS NULL
+				AND diary_entries.watched_on >= make_date($2, 1, 1) AND diary_entries.watched_on < make_date($2 + 1, 1, 1)
+				AND (movies.status = 'published' OR movies.created_by = $1 OR NOT $4::boolean)
+				GROUP BY genre
+				ORDER BY count(*) DESC, genre ASC
+				LIMIT $3`
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID, year, topGenres, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var genre GenreCount
+
+		if err := rows.Scan(&genre.Genre, &genre.Count); err != nil {
+			return nil, err
+		}
+
+		stats.TopGenres = append(stats.TopGenres, genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,247 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DuplicateSimilarity is the trigram similarity above which a movie from the same
+// year is considered a likely duplicate.
+const DuplicateSimilarity = 0.6
+
+// DuplicateCandidate is an existing movie that a new one may duplicate. Titles that
+// match once case, spacing and punctuation are ignored score 1.
+type DuplicateCandidate struct {
+	ID    int64   `json:"id"`
+	Title string  `json:"title"`
+	Year  int32   `json:"year"`
+	Score float64 `json:"score"`
+}
+
+// FindDuplicates returns up to 5 movies, outside the trash, from the same year as
+// movie whose title is the same once normalized or is similar to its title, best
+// matches first. With publishedOnly, movies that aren't published are left out unless
+// viewer created them.
+func (m *MovieModel) FindDuplicates(movie *Movie, publishedOnly bool, viewer int64) ([]*DuplicateCandidate, error) {
+	candidates, err := m.FindDuplicatesMany([]*Movie{movie}, publishedOnly, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return candidates[0], nil
+}
+
+// FindDuplicatesMany runs FindDuplicates for each of the movies in a single query,
+// returning their candidates in the same order.
+func (m *MovieModel) FindDuplicatesMany(movies []*Movie, publishedOnly bool, viewer int64) ([][]*DuplicateCandidate, error) {
+	titles, years := make([]string, len(movies)), make([]int32, len(movies))
+	candidates := make([][]*DuplicateCandidate, len(movies))
+
+	for i, movie := range movies {
+		titles[i], years[i] = movie.Title, movie.Year
+		candidates[i] = []*DuplicateCandidate{}
+	}
+
+	if len(movies) == 0 {
+		return candidates, nil
+	}
+
+	statement := `SELECT input.position, candidates.id, candidates.title, candidates.year, candidates.score
+				FROM unnest($1::text[], $2::integer[]) WITH ORDINALITY AS input (title, year, position)
+				CROSS JOIN LATERAL (
+					SELECT id, title, year, score
+					FROM (
+						SELECT movies.id, movies.title, movies.year,
+							CASE WHEN regexp_replace(lower(movies.title), '[^[:alnum:]]+', '', 'g') = regexp_replace(lower(input.title), '[^[:alnum:]]+', '', 'g')
+								THEN 1 ELSE similarity(movies.title, input.title) END AS score
+						FROM movies
+						WHERE movies.year = input.year AND movies.deleted_at IS NULL
+						AND (movies.status = 'published' OR movies.created_by = $5 OR NOT $4::boolean)
+					) AS scored
+					WHERE score >= $3::float8
+					ORDER BY score DESC, id ASC
+					LIMIT 5
+				) AS candidates
+				ORDER BY input.position, candidates.score DESC, candidates.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, pq.Array(titles), pq.Array(years), DuplicateSimilarity, publishedOnly, viewer)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var position int
+		var candidate DuplicateCandidate
+
+		if err := rows.Scan(&position, &candidate.ID, &candidate.Title, &candidate.Year, &candidate.Score); err != nil {
+			return nil, err
+		}
+
+		candidates[position-1] = append(candidates[position-1], &candidate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// Merge folds the duplicate movie source into target and permanently removes source,
+// leaving a redirect from its ID to target. Reviews, watchlist items, diary entries,
+// credits, titles and external IDs move over to target unless target already has
+// their equivalent, in which case target's own is kept. Source's title becomes an
+// alternative title of target, and its poster is used if target has none. The merge
+// is recorded as a new revision of target edited by userID, and fails with
+// ErrEditConflict if target is no longer at target.Version.
+func (m *MovieModel) Merge(source *Movie, target *Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement := `UPDATE movies
+				SET poster_url = source.poster_url, poster_thumbnail_url = source.poster_thumbnail_url
+				FROM movies AS source
+				WHERE movies.id = $1 AND source.id = $2 AND movies.poster_url = ''`
+
+	if _, err := tx.ExecContext(ctx, statement, target.ID, source.ID); err != nil {
+		return err
+	}
+
+	statement = `UPDATE movies
+				SET version = version + 1
+				WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+				RETURNING version, poster_url, poster_thumbnail_url`
+
+	err = tx.QueryRowContext(ctx, statement, target.ID, target.Version).Scan(&target.Version, &target.PosterURL, &target.PosterThumbnailURL)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if err := insertRevision(ctx, tx, target, userID); err != nil {
+		return err
+	}
+
+	// Each statement moves the rows of source that don't clash with one target
+	// already has. Whatever is left behind is removed along with source.
+	statements := []string{
+		`UPDATE reviews SET movie_id = $2
+			WHERE movie_id = $1
+			AND user_id NOT IN (SELECT user_id FROM reviews WHERE movie_id = $2)`,
+
+		`WITH removed AS (
+			DELETE FROM watchlist_items
+			WHERE movie_id = $1
+			AND user_id IN (SELECT user_id FROM watchlist_items WHERE movie_id = $2)
+			RETURNING user_id, position
+		)
+		UPDATE watchlist_items SET position = watchlist_items.position - 1
+			FROM removed
+			WHERE watchlist_items.user_id = removed.user_id AND watchlist_items.position > removed.position`,
+
+		`UPDATE watchlist_items SET movie_id = $2 WHERE movie_id = $1`,
+
+		`UPDATE diary_entries SET movie_id = $2 WHERE movie_id = $1`,
+
+		`UPDATE movie_credits SET movie_id = $2
+			WHERE movie_id = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM movie_credits AS existing
+				WHERE existing.movie_id = $2 AND existing.person_id = movie_credits.person_id
+				AND existing.role = movie_credits.role AND existing.character = movie_credits.character
+			)`,
+
+		`UPDATE movie_titles SET movie_id = $2
+			WHERE movie_id = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM movie_titles AS existing
+				WHERE existing.movie_id = $2 AND existing.kind = movie_titles.kind
+				AND (existing.locale = movie_titles.locale AND existing.kind = 'translation' OR lower(existing.title) = lower(movie_titles.title))
+			)`,
+
+		`INSERT INTO movie_titles (movie_id, kind, title)
+			SELECT $2, 'alternative', source.title
+			FROM movies AS source, movies AS target
+			WHERE source.id = $1 AND target.id = $2
+			AND lower(source.title) <> lower(target.title)
+			AND NOT EXISTS (
+				SELECT 1 FROM movie_titles
+				WHERE movie_titles.movie_id = $2 AND movie_titles.kind = 'alternative' AND lower(movie_titles.title) = lower(source.title)
+			)`,
+
+		`UPDATE movie_external_ids SET movie_id = $2
+			WHERE movie_id = $1
+			AND source NOT IN (SELECT source FROM movie_external_ids WHERE movie_id = $2)`,
+
+		`UPDATE movie_redirects SET movie_id = $2 WHERE movie_id = $1`,
+
+		`INSERT INTO movie_redirects (old_id, movie_id) VALUES ($1, $2)`,
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement, source.ID, target.ID); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM movies WHERE id = $1 AND deleted_at IS NULL`, source.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	if err := refreshRating(ctx, tx, target.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetRedirect returns the ID of the movie that the movie with the given ID was merged
+// into.
+func (m *MovieModel) GetRedirect(id int64) (int64, error) {
+	statement := `SELECT movie_id FROM movie_redirects WHERE old_id = $1`
+
+	var movieID int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, id).Scan(&movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return movieID, nil
+}
@@ -0,0 +1,242 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateExternalID = errors.New("duplicate external id")
+)
+
+// ExternalIDFormats maps each outside catalogue we sync with to the format of its IDs.
+var ExternalIDFormats = map[string]*regexp.Regexp{
+	"imdb":     regexp.MustCompile(`^tt\d{7,10}$`),
+	"tmdb":     regexp.MustCompile(`^[1-9]\d{0,9}$`),
+	"wikidata": regexp.MustCompile(`^Q[1-9]\d*$`),
+}
+
+// ExternalIDs maps an outside catalogue to the movie's ID in it, such as
+// {"imdb": "tt0111161"}. A movie has at most one ID per catalogue, and no two movies
+// share one.
+type ExternalIDs map[string]string
+
+// externalIDsColumn reads a movie's external IDs as a JSON object.
+const externalIDsColumn = `(SELECT COALESCE(jsonb_object_agg(movie_external_ids.source, movie_external_ids.external_id), '{}')
+				FROM movie_external_ids WHERE movie_external_ids.movie_id = movies.id)`
+
+// Scan reads the JSON object selected by externalIDsColumn.
+func (ids *ExternalIDs) Scan(src any) error {
+	switch value := src.(type) {
+	case []byte:
+		return json.Unmarshal(value, ids)
+	case string:
+		return json.Unmarshal([]byte(value), ids)
+	default:
+		return fmt.Errorf("cannot scan %T into ExternalIDs", src)
+	}
+}
+
+// sources returns the catalogues the movie has IDs in, sorted so that validation
+// errors and query arguments come out in a stable order.
+func (ids ExternalIDs) sources() []string {
+	sources := make([]string, 0, len(ids))
+	for source := range ids {
+		sources = append(sources, source)
+	}
+
+	slices.Sort(sources)
+
+	return sources
+}
+
+func ValidateExternalID(val *validator.Validator, source string, id string) {
+	format, ok := ExternalIDFormats[source]
+
+	val.Check(ok, "source", "must be one of imdb, tmdb or wikidata")
+	val.Check(id != "", "id", "must be provided")
+	val.Check(!ok || id == "" || validator.Matches(id, format), "id", fmt.Sprintf("must be a valid %s id", source))
+}
+
+func ValidateExternalIDs(val *validator.Validator, ids ExternalIDs) {
+	for _, source := range ids.sources() {
+		format, ok := ExternalIDFormats[source]
+		if !ok {
+			val.AddError("external_ids", fmt.Sprintf("contains unknown source %q", source))
+			continue
+		}
+
+		val.Check(validator.Matches(ids[source], format), "external_ids", fmt.Sprintf("contains an invalid %s id", source))
+	}
+}
+
+// GetByExternalID finds the movie holding the given ID in an outside catalogue.
+func (m *MovieModel) GetByExternalID(source string, id string) (*Movie, error) {
+	statement := `SELECT ` + movieColumns + `
+				FROM movies
+				INNER JOIN movie_external_ids ON movie_external_ids.movie_id = movies.id
+				WHERE movie_external_ids.source = $1 AND movie_external_ids.external_id = $2
+				AND movies.deleted_at IS NULL`
+
+	var movie Movie
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, source, id).Scan(movieFields(&movie)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &movie, nil
+}
+
+// ExternalIDMatch is an existing movie holding an external ID, as it was when matched.
+type ExternalIDMatch struct {
+	MovieID   int64
+	Version   int32
+	CreatedBy int64
+	Trashed   bool
+}
+
+// ExternalIDMatches maps a source and an external ID to the existing movie holding it.
+type ExternalIDMatches map[string]map[string]*ExternalIDMatch
+
+// Movies returns the distinct existing movies, in id order, holding any of ids.
+func (matches ExternalIDMatches) Movies(ids ExternalIDs) []*ExternalIDMatch {
+	movies := []*ExternalIDMatch{}
+
+	for source, id := range ids {
+		match, ok := matches[source][id]
+		if !ok {
+			continue
+		}
+
+		if !slices.ContainsFunc(movies, func(movie *ExternalIDMatch) bool { return movie.MovieID == match.MovieID }) {
+			movies = append(movies, match)
+		}
+	}
+
+	slices.SortFunc(movies, func(a, b *ExternalIDMatch) int { return cmp.Compare(a.MovieID, b.MovieID) })
+
+	return movies
+}
+
+// MatchExternalIDs looks up which existing movies, including those in the trash,
+// already hold the external IDs of the given movies.
+func (m *MovieModel) MatchExternalIDs(movies []*Movie) (ExternalIDMatches, error) {
+	sources, ids := []string{}, []string{}
+
+	for _, movie := range movies {
+		for _, source := range movie.ExternalIDs.sources() {
+			sources = append(sources, source)
+			ids = append(ids, movie.ExternalIDs[source])
+		}
+	}
+
+	matches := ExternalIDMatches{}
+
+	if len(ids) == 0 {
+		return matches, nil
+	}
+
+	statement := `SELECT movie_external_ids.source, movie_external_ids.external_id, movies.id, movies.version,
+					COALESCE(movies.created_by, 0), movies.deleted_at IS NOT NULL
+				FROM movie_external_ids
+				INNER JOIN movies ON movies.id = movie_external_ids.movie_id
+				WHERE (movie_external_ids.source, movie_external_ids.external_id) IN (SELECT * FROM unnest($1::text[], $2::text[]))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, pq.Array(sources), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source, id string
+		var match ExternalIDMatch
+
+		if err := rows.Scan(&source, &id, &match.MovieID, &match.Version, &match.CreatedBy, &match.Trashed); err != nil {
+			return nil, err
+		}
+
+		if matches[source] == nil {
+			matches[source] = map[string]*ExternalIDMatch{}
+		}
+
+		matches[source][id] = &match
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// replaceExternalIDs makes movie.ExternalIDs the movie's complete set of external IDs.
+func replaceExternalIDs(ctx context.Context, tx *sql.Tx, movie *Movie) error {
+	statement := `DELETE FROM movie_external_ids
+				WHERE movie_id = $1 AND source <> ALL($2::text[])`
+
+	_, err := tx.ExecContext(ctx, statement, movie.ID, pq.Array(movie.ExternalIDs.sources()))
+	if err != nil {
+		return err
+	}
+
+	return upsertExternalIDs(ctx, tx, []*Movie{movie})
+}
+
+// upsertExternalIDs adds the movies' external IDs to the ones they already have, taking
+// the new ID where a movie already has one from the same source.
+func upsertExternalIDs(ctx context.Context, tx *sql.Tx, movies []*Movie) error {
+	movieIDs, sources, ids := []int64{}, []string{}, []string{}
+
+	for _, movie := range movies {
+		for _, source := range movie.ExternalIDs.sources() {
+			movieIDs = append(movieIDs, movie.ID)
+			sources = append(sources, source)
+			ids = append(ids, movie.ExternalIDs[source])
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	statement := `INSERT INTO movie_external_ids (movie_id, source, external_id)
+				SELECT * FROM unnest($1::bigint[], $2::text[], $3::text[])
+				ON CONFLICT (movie_id, source) DO UPDATE SET external_id = EXCLUDED.external_id`
+
+	_, err := tx.ExecContext(ctx, statement, pq.Array(movieIDs), pq.Array(sources), pq.Array(ids))
+	return externalIDError(err)
+}
+
+// externalIDError maps unique violations on external IDs to ErrDuplicateExternalID.
+func externalIDError(err error) error {
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", ErrDuplicateExternalID, pqErr.Detail)
+	}
+
+	return err
+}
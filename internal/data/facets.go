@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FacetSafelist holds the facets a movie listing can be asked to count.
+var FacetSafelist = []string{"genres", "decade", "runtime_bucket"}
+
+// Facets maps each requested facet to the number of matching movies per value.
+type Facets map[string][]FacetCount
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// facetQueries select the facet name, value, a sort key and a count from the matched
+// movies. Genres are listed most common first; decades and runtime buckets in order.
+var facetQueries = map[string]string{
+	"genres": `SELECT 'genres', genre, -count(*), count(*)
+				FROM matched, unnest(matched.genres) AS genre
+				GROUP BY genre`,
+	"decade": `SELECT 'decade', (year / 10 * 10)::text || 's', year / 10, count(*)
+				FROM matched
+				GROUP BY year / 10`,
+	"runtime_bucket": `SELECT 'runtime_bucket', bucket.label, bucket.position, count(*)
+				FROM matched, LATERAL (
+					SELECT CASE
+							WHEN runtime < 90 THEN '0-89'
+							WHEN runtime < 120 THEN '90-119'
+							WHEN runtime < 150 THEN '120-149'
+							ELSE '150+'
+						END AS label,
+						width_bucket(runtime, ARRAY[90, 120, 150]) AS position
+				) AS bucket
+				GROUP BY bucket.label, bucket.position`,
+}
+
+// facets counts the movies matching the criteria by each of the named facets in a
+// single query.
+func (m *MovieModel) facets(ctx context.Context, criteria MovieCriteria, names []string) (Facets, error) {
+	args := queryArgs{}
+	queries := make([]string, len(names))
+
+	for i, name := range names {
+		queries[i] = facetQueries[name]
+	}
+
+	statement := fmt.Sprintf(`
+				WITH matched AS (
+					SELECT genres, year, runtime
+					FROM movies
+					WHERE %s
+				)
+				%s
+				ORDER BY 1, 3, 2`, criteria.where(&args), strings.Join(queries, "\nUNION ALL\n"))
+
+	rows, err := m.DB.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := Facets{}
+
+	for _, name := range names {
+		facets[name] = []FacetCount{}
+	}
+
+	for rows.Next() {
+		var name string
+		var position int
+		var count FacetCount
+
+		if err := rows.Scan(&name, &count.Value, &position, &count.Count); err != nil {
+			return nil, err
+		}
+
+		facets[name] = append(facets[name], count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
@@ -1,36 +1,114 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"strings"
 
 	"github.com/sparrowsl/greenlight/internal/validator"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type Filters struct {
 	Page         int
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+
+	// Fields optionally limits the columns read to a sparse fieldset, validated
+	// against FieldSafelist.
+	Fields        []string
+	FieldSafelist []string
+
+	// When UseCursor is set the listing is paged with a keyset seek instead of
+	// LIMIT/OFFSET. An empty Cursor requests the first page; otherwise it holds the
+	// next_cursor value returned by the previous page.
+	UseCursor bool
+	Cursor    string
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
 }
 
-func ValidateFilters(val *validator.Validator, filters Filters) {
-	// Check that the page and page_size parameters contain sensible values.
+// cursor is the decoded form of the opaque value handed to clients. It records the
+// sort it was issued for together with the sort key values and id of the last row
+// seen, which is enough to seek to the start of the following page.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     int64    `json:"i"`
+}
+
+func encodeCursor(c cursor) string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(js, &c); err != nil || c.ID < 1 {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// ValidatePagination checks only the page and page_size parameters, for listings
+// that have a fixed order and so take no sort.
+func ValidatePagination(val *validator.Validator, filters Filters) {
 	val.Check(filters.Page > 0, "page", "must be greater than zero")
 	val.Check(filters.Page <= 10_000_000, "page", "must be a maximum of 10 million")
 	val.Check(filters.PageSize > 0, "page_size", "must be greater than zero")
 	val.Check(filters.PageSize <= 100, "page_size", "must be a maximum of 100")
+}
+
+func ValidateFilters(val *validator.Validator, filters Filters) {
+	ValidatePagination(val, filters)
+
+	// Check that every key of the sort parameter matches a value in the safelist, and
+	// that no column is sorted on twice.
+	columns := []string{}
 
-	// Check that the sort parameter matches a value in the safelist.
-	val.Check(validator.PermittedValue(filters.Sort, filters.SortSafelist...), "sort", "invalid sort value")
+	for _, key := range strings.Split(filters.Sort, ",") {
+		val.Check(validator.PermittedValue(key, filters.SortSafelist...), "sort", "invalid sort value")
+		columns = append(columns, strings.TrimPrefix(key, "-"))
+	}
+
+	val.Check(len(columns) <= 3, "sort", "must not contain more than 3 keys")
+	val.Check(validator.Unique(columns), "sort", "must not sort on the same field twice")
+
+	for _, field := range filters.Fields {
+		val.Check(validator.PermittedValue(field, filters.FieldSafelist...), "fields", "invalid field value")
+	}
+
+	val.Check(validator.Unique(filters.Fields), "fields", "must not contain duplicate values")
+
+	if filters.UseCursor {
+		val.Check(filters.Page == 1, "page", "must not be combined with cursor")
+
+		if filters.Cursor != "" {
+			c, err := decodeCursor(filters.Cursor)
+			val.Check(err == nil, "cursor", "must be a next_cursor value from a previous response")
+			val.Check(err != nil || c.Sort == filters.Sort, "cursor", "was issued for a different sort value")
+			val.Check(err != nil || c.Sort != filters.Sort || len(c.Values) == len(columns), "cursor", "must be a next_cursor value from a previous response")
+		}
+	}
 }
 
 func calculateMetadata(totalRecords int, page int, pageSize int) Metadata {
@@ -47,28 +125,111 @@ func calculateMetadata(totalRecords int, page int, pageSize int) Metadata {
 	}
 }
 
+// calculateCursorMetadata builds the metadata for a keyset page. The caller fetches
+// one row more than the page size and passes a non-zero lastID, with the sort key
+// values of that row, only when another page exists.
+func calculateCursorMetadata(filters Filters, lastValues []string, lastID int64) Metadata {
+	metadata := Metadata{PageSize: filters.PageSize}
+
+	if lastID > 0 {
+		metadata.NextCursor = encodeCursor(cursor{Sort: filters.Sort, Values: lastValues, ID: lastID})
+	}
+
+	return metadata
+}
+
 func (filters Filters) limit() int {
+	if filters.UseCursor {
+		return filters.PageSize + 1
+	}
+
 	return filters.PageSize
 }
 
 func (filters Filters) offset() int {
+	if filters.UseCursor {
+		return 0
+	}
+
 	return (filters.Page - 1) * filters.PageSize
 }
 
-func (filters Filters) sortColumn() string {
-	for _, safeValue := range filters.SortSafelist {
-		if filters.Sort == safeValue {
-			return strings.TrimPrefix(filters.Sort, "-")
+// sortKey is one key of an ORDER BY list: a column or expression and a direction.
+type sortKey struct {
+	expr      string
+	direction string
+}
+
+// sortKeys returns the keys of the sort parameter in order. Each key is checked
+// against the safelist again so that nothing unvalidated reaches the SQL.
+func (filters Filters) sortKeys() []sortKey {
+	keys := []sortKey{}
+
+	for _, key := range strings.Split(filters.Sort, ",") {
+		if !validator.PermittedValue(key, filters.SortSafelist...) {
+			panic("unsafe sort parameter: " + key)
+		}
+
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
 		}
+
+		keys = append(keys, sortKey{expr: strings.TrimPrefix(key, "-"), direction: direction})
 	}
 
-	panic("unsafe sort parameter: " + filters.Sort)
+	return keys
 }
 
-func (filters Filters) sortDirection() string {
-	if strings.HasPrefix(filters.Sort, "-") {
-		return "DESC"
+// orderBy returns the ORDER BY list for the keys, followed by tiebreak ascending
+// unless it is already one of the keys, so that the order is total and pages don't
+// overlap.
+func orderBy(keys []sortKey, tiebreak string) string {
+	terms := []string{}
+	tied := true
+
+	for _, key := range keys {
+		terms = append(terms, key.expr+" "+key.direction)
+		tied = tied && key.expr != tiebreak
 	}
 
-	return "ASC"
+	if tied {
+		terms = append(terms, tiebreak+" ASC")
+	}
+
+	return strings.Join(terms, ", ")
+}
+
+// keysetCondition returns the WHERE clause that seeks past the row encoded in the
+// cursor, for a listing ordered by the keys followed by id ASC. Keys may sort in
+// different directions, so the row comparison is spelt out key by key. It returns
+// "TRUE" when there is no cursor to seek from.
+func (filters Filters) keysetCondition(keys []sortKey, args *queryArgs) string {
+	if !filters.UseCursor || filters.Cursor == "" {
+		return "TRUE"
+	}
+
+	c, err := decodeCursor(filters.Cursor)
+	if err != nil || len(c.Values) != len(keys) {
+		panic("unvalidated cursor parameter: " + filters.Cursor)
+	}
+
+	alternatives := []string{}
+	equal := []string{}
+
+	for i, key := range keys {
+		operator := ">"
+		if key.direction == "DESC" {
+			operator = "<"
+		}
+
+		value := args.add(c.Values[i])
+
+		alternatives = append(alternatives, strings.Join(append(equal, fmt.Sprintf("%s %s %s", key.expr, operator, value)), " AND "))
+		equal = append(equal, fmt.Sprintf("%s = %s", key.expr, value))
+	}
+
+	alternatives = append(alternatives, strings.Join(append(equal, fmt.Sprintf("id > %s", args.add(c.ID))), " AND "))
+
+	return "((" + strings.Join(alternatives, ") OR (") + "))"
 }
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func TestValidateFiltersCursor(t *testing.T) {
+	safelist := []string{"id", "title", "year", "-id", "-title", "-year"}
+
+	tests := []struct {
+		name    string
+		filters Filters
+		want    map[string]string
+	}{
+		{
+			name:    "first page",
+			filters: Filters{Page: 1, PageSize: 20, Sort: "title", UseCursor: true},
+		},
+		{
+			name:    "next page",
+			filters: Filters{Page: 1, PageSize: 20, Sort: "-year,title", UseCursor: true, Cursor: encodeCursor(cursor{Sort: "-year,title", Values: []string{"2016", "Moana"}, ID: 7})},
+		},
+		{
+			name:    "combined with page",
+			filters: Filters{Page: 2, PageSize: 20, Sort: "title", UseCursor: true},
+			want:    map[string]string{"page": "must not be combined with cursor"},
+		},
+		{
+			name:    "not base64",
+			filters: Filters{Page: 1, PageSize: 20, Sort: "title", UseCursor: true, Cursor: "!!!"},
+			want:    map[string]string{"cursor": "must be a next_cursor value from a previous response"},
+		},
+		{
+			name:    "missing id",
+			filters: Filters{Page: 1, PageSize: 20, Sort: "title", UseCursor: true, Cursor: encodeCursor(cursor{Sort: "title", Values: []string{"Moana"}})},
+			want:    map[string]string{"cursor": "must be a next_cursor value from a previous response"},
+		},
+		{
+			name:    "different sort",
+			filters: Filters{Page: 1, PageSize: 20, Sort: "year", UseCursor: true, Cursor: encodeCursor(cursor{Sort: "title", Values: []string{"Moana"}, ID: 7})},
+			want:    map[string]string{"cursor": "was issued for a different sort value"},
+		},
+		{
+			name:    "wrong number of values",
+			filters: Filters{Page: 1, PageSize: 20, Sort: "title", UseCursor: true, Cursor: encodeCursor(cursor{Sort: "title", Values: []string{"Moana", "2016"}, ID: 7})},
+			want:    map[string]string{"cursor": "must be a next_cursor value from a previous response"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filters.SortSafelist = safelist
+
+			val := validator.New()
+			ValidateFilters(val, tt.filters)
+
+			want := tt.want
+			if want == nil {
+				want = map[string]string{}
+			}
+
+			if !maps.Equal(val.Errors, want) {
+				t.Errorf("got errors %v; want %v", val.Errors, want)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		sort     string
+		cursor   string
+		want     string
+		wantArgs []any
+	}{
+		{
+			name: "first page",
+			sort: "title",
+			want: "TRUE",
+		},
+		{
+			name:     "ascending key",
+			sort:     "title",
+			cursor:   encodeCursor(cursor{Sort: "title", Values: []string{"Moana"}, ID: 7}),
+			want:     "((title > $1) OR (title = $1 AND id > $2))",
+			wantArgs: []any{"Moana", int64(7)},
+		},
+		{
+			name:     "mixed directions",
+			sort:     "-year,title",
+			cursor:   encodeCursor(cursor{Sort: "-year,title", Values: []string{"2016", "Moana"}, ID: 7}),
+			want:     "((year < $1) OR (year = $1 AND title > $2) OR (year = $1 AND title = $2 AND id > $3))",
+			wantArgs: []any{"2016", "Moana", int64(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := Filters{
+				Sort:         tt.sort,
+				SortSafelist: []string{"title", "-year"},
+				UseCursor:    true,
+				Cursor:       tt.cursor,
+			}
+
+			var args queryArgs
+
+			got := filters.keysetCondition(filters.sortKeys(), &args)
+			if got != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("got args %v; want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
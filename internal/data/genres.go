@@ -0,0 +1,352 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateGenre = errors.New("duplicate genre")
+	ErrMergeTarget    = errors.New("invalid merge target")
+)
+
+type Genre struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Aliases    []string `json:"aliases"`
+	MovieCount int      `json:"movie_count"`
+}
+
+// GenreVocabulary maps every genre name and alias, case-insensitively, to the
+// canonical genre name.
+type GenreVocabulary map[string]string
+
+// Canonical returns the canonical name for a genre name or alias.
+func (v GenreVocabulary) Canonical(name string) (string, bool) {
+	canonical, ok := v[strings.ToLower(strings.TrimSpace(name))]
+	return canonical, ok
+}
+
+func ValidateGenre(val *validator.Validator, genre *Genre) {
+	val.Check(genre.Name != "", "name", "must be provided")
+	val.Check(len(genre.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	val.Check(genre.Aliases != nil, "aliases", "must be provided")
+	val.Check(len(genre.Aliases) <= 20, "aliases", "must not contain more than 20 aliases")
+	val.Check(validator.Unique(genre.Aliases), "aliases", "must not contain duplicate values")
+
+	for _, alias := range genre.Aliases {
+		val.Check(alias != "", "aliases", "must not contain empty values")
+		val.Check(len(alias) <= 100, "aliases", "must not contain values more than 100 bytes long")
+		val.Check(!strings.EqualFold(alias, genre.Name), "aliases", "must not contain the genre name")
+	}
+}
+
+type GenreModel struct {
+	DB *sql.DB
+}
+
+// GetAll lists the genres with their aliases and the number of movies, outside the
+// trash, that use each one. With publishedOnly, only published movies are counted.
+func (m *GenreModel) GetAll(publishedOnly bool) ([]*Genre, error) {
+	statement := `SELECT genres.id, genres.name,
+					COALESCE(array_agg(genre_aliases.alias::text ORDER BY genre_aliases.alias) FILTER (WHERE genre_aliases.alias IS NOT NULL), '{}'),
+					(SELECT count(*) FROM movies
+						WHERE movies.genres @> ARRAY[genres.name::text] AND movies.deleted_at IS NULL
+						AND (movies.status = 'published' OR NOT $1::boolean))
+				FROM genres
+				LEFT JOIN genre_aliases ON genre_aliases.genre_id = genres.id
+				GROUP BY genres.id
+				ORDER BY genres.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*Genre{}
+
+	for rows.Next() {
+		var genre Genre
+
+		if err := rows.Scan(&genre.ID, &genre.Name, pq.Array(&genre.Aliases), &genre.MovieCount); err != nil {
+			return nil, err
+		}
+
+		genres = append(genres, &genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+func (m *GenreModel) Get(id int64) (*Genre, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	statement := `SELECT genres.id, genres.name,
+					COALESCE(array_agg(genre_aliases.alias::text ORDER BY genre_aliases.alias) FILTER (WHERE genre_aliases.alias IS NOT NULL), '{}')
+				FROM genres
+				LEFT JOIN genre_aliases ON genre_aliases.genre_id = genres.id
+				WHERE genres.id = $1
+				GROUP BY genres.id`
+
+	var genre Genre
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, id).Scan(&genre.ID, &genre.Name, pq.Array(&genre.Aliases))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &genre, nil
+}
+
+// Vocabulary loads every genre name and alias for validating movie genres.
+func (m *GenreModel) Vocabulary() (GenreVocabulary, error) {
+	statement := `SELECT name::text, name::text FROM genres
+				UNION ALL
+				SELECT genre_aliases.alias::text, genres.name::text
+				FROM genre_aliases
+				INNER JOIN genres ON genres.id = genre_aliases.genre_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vocabulary := GenreVocabulary{}
+
+	for rows.Next() {
+		var name, canonical string
+
+		if err := rows.Scan(&name, &canonical); err != nil {
+			return nil, err
+		}
+
+		vocabulary[strings.ToLower(name)] = canonical
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return vocabulary, nil
+}
+
+func (m *GenreModel) Insert(genre *Genre) error {
+	statement := `INSERT INTO genres (name)
+				VALUES ($1)
+				RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, statement, genre.Name).Scan(&genre.ID); err != nil {
+		return genreError(err)
+	}
+
+	if err := insertAliases(ctx, tx, genre.ID, genre.Aliases); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update renames the genre and replaces its aliases. Renaming rewrites every movie
+// using the old name, recording a revision edited by userID for each, and keeps the
+// old name as an alias so clients still sending it are mapped to the new one.
+func (m *GenreModel) Update(genre *Genre, oldName string, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE genres SET name = $1 WHERE id = $2`, genre.Name, genre.ID)
+	if err != nil {
+		return genreError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM genre_aliases WHERE genre_id = $1`, genre.ID)
+	if err != nil {
+		return err
+	}
+
+	if oldName != genre.Name {
+		if err := replaceMovieGenre(ctx, tx, oldName, genre.Name, userID); err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(oldName, genre.Name) && !containsFold(genre.Aliases, oldName) {
+			genre.Aliases = append(genre.Aliases, oldName)
+		}
+	}
+
+	if err := insertAliases(ctx, tx, genre.ID, genre.Aliases); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Merge folds source into target: movies using the source genre are rewritten to use
+// the target, and the source name and aliases become aliases of the target. It fails
+// with ErrRecordNotFound if source no longer exists, and with ErrMergeTarget if target
+// is source or no longer exists.
+func (m *GenreModel) Merge(source *Genre, target *Genre, userID int64) error {
+	if source.ID == target.ID {
+		return ErrMergeTarget
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock both genres so neither can be removed or merged elsewhere half way through.
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM genres WHERE id = ANY($1) FOR UPDATE`, pq.Array([]int64{source.ID, target.ID}))
+	if err != nil {
+		return err
+	}
+
+	found := map[int64]bool{}
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+
+		found[id] = true
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	switch {
+	case !found[source.ID]:
+		return ErrRecordNotFound
+	case !found[target.ID]:
+		return ErrMergeTarget
+	}
+
+	if err := replaceMovieGenre(ctx, tx, source.Name, target.Name, userID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE genre_aliases SET genre_id = $1 WHERE genre_id = $2`, target.ID, source.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM genres WHERE id = $1`, source.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := insertAliases(ctx, tx, target.ID, []string{source.Name}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertAliases(ctx context.Context, tx *sql.Tx, genreID int64, aliases []string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	statement := `INSERT INTO genre_aliases (alias, genre_id)
+				SELECT unnest($1::text[]), $2`
+
+	_, err := tx.ExecContext(ctx, statement, pq.Array(aliases), genreID)
+	return genreError(err)
+}
+
+// replaceMovieGenre swaps oldName for newName in every movie's genres, dropping the
+// duplicate if a movie already had both, and records the new versions as revisions.
+func replaceMovieGenre(ctx context.Context, tx *sql.Tx, oldName string, newName string, userID int64) error {
+	statement := `WITH updated AS (
+					UPDATE movies
+					SET genres = ARRAY(
+							SELECT t.genre
+							FROM unnest(array_replace(movies.genres, $1::text, $2::text)) WITH ORDINALITY AS t(genre, position)
+							GROUP BY t.genre
+							ORDER BY min(t.position)
+						),
+						version = version + 1
+					WHERE genres @> ARRAY[$1::text]
+					RETURNING id, version, title, year, runtime, genres
+				)
+				INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, edited_by)
+				SELECT id, version, title, year, runtime, genres, NULLIF($3::bigint, 0) FROM updated`
+
+	_, err := tx.ExecContext(ctx, statement, oldName, newName, userID)
+	return err
+}
+
+// genreError maps unique violations on genre names and aliases to ErrDuplicateGenre.
+func genreError(err error) error {
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", ErrDuplicateGenre, pqErr.Detail)
+	}
+
+	return err
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
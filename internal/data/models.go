@@ -12,6 +12,14 @@ var (
 
 type Models struct {
 	Movies      MovieModel
+	Revisions   RevisionModel
+	Titles      MovieTitleModel
+	Genres      GenreModel
+	Reviews     ReviewModel
+	Watchlists  WatchlistModel
+	Diary       DiaryModel
+	People      PersonModel
+	Credits     CreditModel
 	Users       UserModel
 	Tokens      TokenModel
 	Permissions PermissionModel
@@ -20,6 +28,14 @@ type Models struct {
 func NewModel(db *sql.DB) Models {
 	return Models{
 		Movies:      MovieModel{DB: db},
+		Revisions:   RevisionModel{DB: db},
+		Titles:      MovieTitleModel{DB: db},
+		Genres:      GenreModel{DB: db},
+		Reviews:     ReviewModel{DB: db},
+		Watchlists:  WatchlistModel{DB: db},
+		Diary:       DiaryModel{DB: db},
+		People:      PersonModel{DB: db},
+		Credits:     CreditModel{DB: db},
 		Users:       UserModel{DB: db},
 		Tokens:      TokenModel{DB: db},
 		Permissions: PermissionModel{DB: db},
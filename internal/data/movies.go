@@ -3,8 +3,10 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -19,13 +21,267 @@ type Movie struct {
 	Genres    []string  `json:"genres,omitempty"`
 	Version   int32     `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// CreatedBy is the ID of the user who created the movie, and owns it. It is zero
+	// for movies created before ownership was recorded or whose creator is gone.
+	CreatedBy int64 `json:"created_by,omitempty"`
+
+	// AverageRating and RatingCount aggregate the movie's reviews.
+	AverageRating float64 `json:"average_rating"`
+	RatingCount   int     `json:"rating_count"`
+
+	// PosterURL and PosterThumbnailURL locate the uploaded poster and its thumbnail.
+	PosterURL          string `json:"poster_url,omitempty"`
+	PosterThumbnailURL string `json:"poster_thumbnail_url,omitempty"`
+
+	// Status is where the movie is in the publication workflow; only published movies
+	// are shown to plain readers. PublishAt schedules a movie in review to be
+	// published automatically.
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+
+	// ExternalIDs holds the movie's IDs in the outside catalogues we sync with.
+	ExternalIDs ExternalIDs `json:"external_ids,omitempty"`
+
+	// OriginalTitle and TitleLocale are set when Title has been replaced by the
+	// translation for a locale the client asked for.
+	OriginalTitle string `json:"original_title,omitempty"`
+	TitleLocale   string `json:"title_locale,omitempty"`
+
+	// Credits are only loaded when a client asks for them.
+	Credits []*Credit `json:"credits,omitempty"`
+
+	// DeletedAt is set while the movie sits in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Headline is the title with the search terms marked up, only set on listings
+	// that asked for highlighting.
+	Headline string `json:"headline,omitempty"`
+
+	// RuntimeFormat is the format Runtime is written in, "<n> mins" when empty.
+	RuntimeFormat RuntimeFormat `json:"-"`
+}
+
+// plainMovie has the fields of Movie without its MarshalJSON method.
+type plainMovie Movie
+
+// movieJSON is how a movie is written, with its runtime in the format it was given.
+type movieJSON struct {
+	plainMovie
+	Runtime *FormattedRuntime `json:"runtime,omitempty"`
+}
+
+func (movie *Movie) json() movieJSON {
+	return movieJSON{plainMovie(*movie), movie.Runtime.formatted(movie.RuntimeFormat)}
+}
+
+func (movie Movie) MarshalJSON() ([]byte, error) {
+	return json.Marshal(movie.json())
 }
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func ValidateMovie(val *validator.Validator, movie *Movie) {
+// movieField describes a movie field that can be selected on its own: its JSON name,
+// its column, where to scan it and how to read it back for a sparse response.
+type movieField struct {
+	name   string
+	column string
+	dest   func(movie *Movie) any
+	value  func(movie *Movie) any
+}
+
+var movieFieldList = []movieField{
+	{"id", "movies.id", func(m *Movie) any { return &m.ID }, func(m *Movie) any { return m.ID }},
+	{"title", "movies.title", func(m *Movie) any { return &m.Title }, func(m *Movie) any { return m.Title }},
+	{"year", "movies.year", func(m *Movie) any { return &m.Year }, func(m *Movie) any { return m.Year }},
+	{"runtime", "movies.runtime", func(m *Movie) any { return &m.Runtime }, func(m *Movie) any { return FormattedRuntime{m.Runtime, m.RuntimeFormat} }},
+	{"created_at", "movies.created_at", func(m *Movie) any { return &m.CreatedAt }, func(m *Movie) any { return m.CreatedAt }},
+	{"created_by", "COALESCE(movies.created_by, 0)", func(m *Movie) any { return &m.CreatedBy }, func(m *Movie) any { return m.CreatedBy }},
+	{"genres", "movies.genres", func(m *Movie) any { return pq.Array(&m.Genres) }, func(m *Movie) any { return m.Genres }},
+	{"version", "movies.version", func(m *Movie) any { return &m.Version }, func(m *Movie) any { return m.Version }},
+	{"average_rating", "movies.rating_average", func(m *Movie) any { return &m.AverageRating }, func(m *Movie) any { return m.AverageRating }},
+	{"rating_count", "movies.rating_count", func(m *Movie) any { return &m.RatingCount }, func(m *Movie) any { return m.RatingCount }},
+	{"poster_url", "movies.poster_url", func(m *Movie) any { return &m.PosterURL }, func(m *Movie) any { return m.PosterURL }},
+	{"poster_thumbnail_url", "movies.poster_thumbnail_url", func(m *Movie) any { return &m.PosterThumbnailURL }, func(m *Movie) any { return m.PosterThumbnailURL }},
+	{"status", "movies.status", func(m *Movie) any { return &m.Status }, func(m *Movie) any { return m.Status }},
+	{"publish_at", "movies.publish_at", func(m *Movie) any { return &m.PublishAt }, func(m *Movie) any { return m.PublishAt }},
+	{"external_ids", externalIDsColumn, func(m *Movie) any { return &m.ExternalIDs }, func(m *Movie) any { return m.ExternalIDs }},
+}
+
+// MovieFieldSafelist holds the field names accepted in a sparse fieldset.
+var MovieFieldSafelist = func() []string {
+	names := make([]string, len(movieFieldList))
+	for i, field := range movieFieldList {
+		names[i] = field.name
+	}
+	return names
+}()
+
+// movieColumns are the columns read into a Movie by every full movie query, in the
+// order of the destinations returned by movieFields.
+var movieColumns, movieFields = movieSelection(nil)
+
+// movieSelection returns the columns for the named fields, or for every field when
+// none are named, and a function returning the scan destinations for those columns.
+// The id, version, rating aggregates, status and owner are always selected, since
+// paging, ETags and deciding who may see the movie depend on them.
+func movieSelection(names []string) (string, func(movie *Movie) []any) {
+	selected := []movieField{}
+
+	for _, field := range movieFieldList {
+		if len(names) == 0 || validator.PermittedValue(field.name, "id", "version", "average_rating", "rating_count", "status", "created_by") || validator.PermittedValue(field.name, names...) {
+			selected = append(selected, field)
+		}
+	}
+
+	columns := make([]string, len(selected))
+	for i, field := range selected {
+		columns[i] = field.column
+	}
+
+	dest := func(movie *Movie) []any {
+		dest := make([]any, len(selected))
+		for i, field := range selected {
+			dest[i] = field.dest(movie)
+		}
+		return dest
+	}
+
+	return strings.Join(columns, ", "), dest
+}
+
+// Project returns the named fields of the movie keyed by their JSON names, for a
+// sparse fieldset response. The id is always included, as are a search headline and
+// credits when the movie has them, and the original title of a translated one.
+func (movie *Movie) Project(names []string) map[string]any {
+	projection := map[string]any{"id": movie.ID}
+
+	for _, field := range movieFieldList {
+		if validator.PermittedValue(field.name, names...) {
+			projection[field.name] = field.value(movie)
+		}
+	}
+
+	if movie.Headline != "" {
+		projection["headline"] = movie.Headline
+	}
+
+	if movie.TitleLocale != "" && validator.PermittedValue("title", names...) {
+		projection["original_title"] = movie.OriginalTitle
+		projection["title_locale"] = movie.TitleLocale
+	}
+
+	if movie.Credits != nil {
+		projection["credits"] = movie.Credits
+	}
+
+	return projection
+}
+
+// MovieCriteria holds the search parameters of a movie listing. Nil bounds are not
+// applied.
+type MovieCriteria struct {
+	Title         string // websearch syntax: "quoted phrases", -exclusions and OR
+	Genres        []string
+	YearMin       *int
+	YearMax       *int
+	RuntimeMin    *int
+	RuntimeMax    *int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	MinRating     *int
+	CreatedBy     *int64
+
+	// Statuses narrows the listing to movies in any of the given statuses.
+	// PublishedOnly hides every movie that isn't published, whatever Statuses asks for,
+	// except for the Viewer's own movies.
+	Statuses      []string
+	PublishedOnly bool
+	Viewer        int64
+
+	// Person narrows the listing to movies crediting the person, optionally only in
+	// the given Role.
+	Person *int
+	Role   string
+
+	// Fuzzy matches the title by trigram word similarity instead of full-text
+	// search, so misspelt titles still find results.
+	Fuzzy bool
+
+	// Highlight asks for a ts_headline snippet of each matching title.
+	Highlight bool
+}
+
+// MovieSuggestion is a lightweight title match used for search-as-you-type.
+type MovieSuggestion struct {
+	ID    int64   `json:"id"`
+	Title string  `json:"title"`
+	Year  int32   `json:"year"`
+	Score float64 `json:"score"`
+}
+
+func ValidateMovieCriteria(val *validator.Validator, criteria MovieCriteria) {
+	currentYear := time.Now().Year()
+
+	if criteria.YearMin != nil {
+		val.Check(*criteria.YearMin >= 1888, "year_min", "must be greater than 1888")
+		val.Check(*criteria.YearMin <= currentYear, "year_min", "must not be in the future")
+	}
+
+	if criteria.YearMax != nil {
+		val.Check(*criteria.YearMax >= 1888, "year_max", "must be greater than 1888")
+		val.Check(*criteria.YearMax <= currentYear, "year_max", "must not be in the future")
+	}
+
+	if criteria.YearMin != nil && criteria.YearMax != nil {
+		val.Check(*criteria.YearMin <= *criteria.YearMax, "year_min", "must not be greater than year_max")
+	}
+
+	if criteria.RuntimeMin != nil {
+		val.Check(*criteria.RuntimeMin > 0, "runtime_min", "must be a positive integer")
+	}
+
+	if criteria.RuntimeMax != nil {
+		val.Check(*criteria.RuntimeMax > 0, "runtime_max", "must be a positive integer")
+	}
+
+	if criteria.RuntimeMin != nil && criteria.RuntimeMax != nil {
+		val.Check(*criteria.RuntimeMin <= *criteria.RuntimeMax, "runtime_min", "must not be greater than runtime_max")
+	}
+
+	if criteria.MinRating != nil {
+		val.Check(*criteria.MinRating >= 1, "min_rating", "must be at least 1")
+		val.Check(*criteria.MinRating <= 10, "min_rating", "must be at most 10")
+	}
+
+	if criteria.CreatedAfter != nil && criteria.CreatedBefore != nil {
+		val.Check(criteria.CreatedAfter.Before(*criteria.CreatedBefore), "created_after", "must be earlier than created_before")
+	}
+
+	if criteria.CreatedBy != nil {
+		val.Check(*criteria.CreatedBy > 0, "created_by", "must be a positive integer or me")
+	}
+
+	for _, status := range criteria.Statuses {
+		val.Check(validator.PermittedValue(status, MovieStatuses...), "status", "must only contain draft, in_review, published or archived")
+	}
+
+	if criteria.Person != nil {
+		val.Check(*criteria.Person > 0, "person", "must be a positive integer")
+	}
+
+	if criteria.Role != "" {
+		val.Check(validator.PermittedValue(criteria.Role, CreditRoles...), "role", "must be one of director, writer or actor")
+		val.Check(criteria.Person != nil, "role", "requires a person")
+	}
+}
+
+// ValidateMovie checks the movie's fields. Genres are checked against the managed
+// vocabulary: aliases are rewritten in place to their canonical names and unknown
+// genres are rejected.
+func ValidateMovie(val *validator.Validator, movie *Movie, genres GenreVocabulary) {
 	val.Check(movie.Title != "", "title", "must be provided")
 	val.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
 
@@ -36,80 +292,531 @@ func ValidateMovie(val *validator.Validator, movie *Movie) {
 	val.Check(movie.Runtime != 0, "runtime", "must be provided")
 	val.Check(movie.Runtime > 0, "runtime", "must be a positive integer")
 
+	for i, genre := range movie.Genres {
+		canonical, ok := genres.Canonical(genre)
+		if !ok {
+			val.AddError("genres", fmt.Sprintf("contains unknown genre %q", genre))
+			continue
+		}
+
+		movie.Genres[i] = canonical
+	}
+
 	val.Check(movie.Genres != nil, "genres", "must be provided")
 	val.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
 	val.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
 	val.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+
+	ValidateExternalIDs(val, movie.ExternalIDs)
+}
+
+// Insert creates a movie owned by userID, with its external IDs, and records its first
+// revision as edited by userID.
+func (m *MovieModel) Insert(movie *Movie, userID int64) error {
+	statement := `INSERT INTO movies (title, year, runtime, genres, created_by)
+                VALUES ($1, $2, $3, $4, NULLIF($5::bigint, 0))
+                RETURNING id, created_at, version, status, COALESCE(created_by, 0)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, statement, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), userID)
+	if err := row.Scan(&movie.ID, &movie.CreatedAt, &movie.Version, &movie.Status, &movie.CreatedBy); err != nil {
+		return err
+	}
+
+	if err := insertRevision(ctx, tx, movie, userID); err != nil {
+		return err
+	}
+
+	if err := upsertExternalIDs(ctx, tx, []*Movie{movie}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpsertMany saves the movies in batches inside a single transaction, so that either
+// all of them are saved or none are. Movies without an ID are created with their first
+// revisions, owned by userID. Movies with an ID overwrite the title, year, runtime and
+// genres of that existing movie outright, recording a new revision, and have their
+// external IDs merged into the ones it already has. It fails with ErrEditConflict if
+// any existing movie is no longer at movie.Version or is in the trash.
+func (m *MovieModel) UpsertMany(movies []*Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(movies); start += insertBatchSize {
+		end := min(start+insertBatchSize, len(movies))
+
+		created, updated := []*Movie{}, []*Movie{}
+		for _, movie := range movies[start:end] {
+			if movie.ID == 0 {
+				created = append(created, movie)
+			} else {
+				updated = append(updated, movie)
+			}
+		}
+
+		if len(created) > 0 {
+			if err := insertBatch(ctx, tx, created, userID); err != nil {
+				return err
+			}
+		}
+
+		if len(updated) > 0 {
+			if err := updateBatch(ctx, tx, updated, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := upsertExternalIDs(ctx, tx, movies[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertBatchSize keeps a batch well below PostgreSQL's limit of 65535 parameters.
+const insertBatchSize = 500
+
+func insertBatch(ctx context.Context, tx *sql.Tx, movies []*Movie, userID int64) error {
+	// Take the IDs from the sequence up front so that each returned row can be matched
+	// to its movie by ID; RETURNING makes no promise about the order of the rows.
+	statement := `SELECT nextval(pg_get_serial_sequence('movies', 'id')) FROM generate_series(1, $1)`
+
+	rows, err := tx.QueryContext(ctx, statement, len(movies))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]*Movie, len(movies))
+
+	i := 0
+	for rows.Next() {
+		if err := rows.Scan(&movies[i].ID); err != nil {
+			return err
+		}
+		byID[movies[i].ID] = movies[i]
+		i++
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	args := queryArgs{}
+	editor := args.add(userID)
+
+	values := make([]string, len(movies))
+	for i, movie := range movies {
+		values[i] = fmt.Sprintf("(%s::bigint, %s, %s::integer, %s::integer, %s::text[], NULLIF(%s::bigint, 0))",
+			args.add(movie.ID), args.add(movie.Title), args.add(movie.Year), args.add(movie.Runtime), args.add(pq.Array(movie.Genres)), editor)
+	}
+
+	statement = fmt.Sprintf(`
+				WITH inserted AS (
+					INSERT INTO movies (id, title, year, runtime, genres, created_by)
+					VALUES %s
+					RETURNING id, created_at, version, status, COALESCE(created_by, 0) AS created_by, title, year, runtime, genres
+				), revisions AS (
+					INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, edited_by)
+					SELECT id, version, title, year, runtime, genres, NULLIF(%s::bigint, 0) FROM inserted
+				)
+				SELECT id, created_at, version, status, created_by FROM inserted`, strings.Join(values, ", "), editor)
+
+	rows, err = tx.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var inserted Movie
+
+		if err := rows.Scan(&id, &inserted.CreatedAt, &inserted.Version, &inserted.Status, &inserted.CreatedBy); err != nil {
+			return err
+		}
+
+		movie := byID[id]
+		movie.CreatedAt, movie.Version, movie.Status, movie.CreatedBy = inserted.CreatedAt, inserted.Version, inserted.Status, inserted.CreatedBy
+	}
+
+	return rows.Err()
+}
+
+// updateBatch overwrites the title, year, runtime and genres of the existing movies with
+// the given IDs, and records their new versions as revisions. It returns
+// ErrEditConflict if any of them is no longer at movie.Version or has been moved to the
+// trash in the meantime.
+func updateBatch(ctx context.Context, tx *sql.Tx, movies []*Movie, userID int64) error {
+	args := queryArgs{}
+	editor := args.add(userID)
+
+	byID := make(map[int64]*Movie, len(movies))
+
+	values := make([]string, len(movies))
+	for i, movie := range movies {
+		values[i] = fmt.Sprintf("(%s::bigint, %s::integer, %s::text, %s::integer, %s::integer, %s::text[])",
+			args.add(movie.ID), args.add(movie.Version), args.add(movie.Title), args.add(movie.Year), args.add(movie.Runtime), args.add(pq.Array(movie.Genres)))
+		byID[movie.ID] = movie
+	}
+
+	statement := fmt.Sprintf(`
+				WITH input (id, version, title, year, runtime, genres) AS (
+					VALUES %s
+				), updated AS (
+					UPDATE movies
+					SET title = input.title, year = input.year, runtime = input.runtime, genres = input.genres, version = movies.version + 1
+					FROM input
+					WHERE movies.id = input.id AND movies.version = input.version AND movies.deleted_at IS NULL
+					RETURNING movies.id, movies.created_at, movies.version, movies.title, movies.year, movies.runtime, movies.genres
+				), revisions AS (
+					INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, edited_by)
+					SELECT id, version, title, year, runtime, genres, NULLIF(%s::bigint, 0) FROM updated
+				)
+				SELECT id, created_at, version FROM updated`, strings.Join(values, ", "), editor)
+
+	rows, err := tx.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id int64
+		var createdAt time.Time
+		var version int32
+
+		if err := rows.Scan(&id, &createdAt, &version); err != nil {
+			return err
+		}
+
+		byID[id].CreatedAt, byID[id].Version = createdAt, version
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if count != len(movies) {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// where returns the WHERE clause matching the criteria, adding its arguments to args.
+func (criteria MovieCriteria) where(args *queryArgs) string {
+	conditions := []string{"deleted_at IS NULL"}
+
+	// Titles are matched against the original title and against every translated or
+	// alternative title of the movie.
+	switch {
+	case criteria.Title != "" && criteria.Fuzzy:
+		title := args.add(criteria.Title)
+		conditions = append(conditions, fmt.Sprintf(`(%[1]s <%% title OR EXISTS (
+					SELECT 1 FROM movie_titles WHERE movie_titles.movie_id = movies.id AND %[1]s <%% movie_titles.title))`, title))
+	case criteria.Title != "":
+		query := criteria.tsquery(args)
+		conditions = append(conditions, fmt.Sprintf(`(search_vector @@ %[1]s OR EXISTS (
+					SELECT 1 FROM movie_titles WHERE movie_titles.movie_id = movies.id AND movie_titles.search_vector @@ %[1]s))`, query))
+	}
+
+	if len(criteria.Genres) > 0 {
+		conditions = append(conditions, fmt.Sprintf("genres @> %s", args.add(pq.Array(criteria.Genres))))
+	}
+
+	if criteria.YearMin != nil {
+		conditions = append(conditions, fmt.Sprintf("year >= %s", args.add(*criteria.YearMin)))
+	}
+
+	if criteria.YearMax != nil {
+		conditions = append(conditions, fmt.Sprintf("year <= %s", args.add(*criteria.YearMax)))
+	}
+
+	if criteria.RuntimeMin != nil {
+		conditions = append(conditions, fmt.Sprintf("runtime >= %s", args.add(*criteria.RuntimeMin)))
+	}
+
+	if criteria.RuntimeMax != nil {
+		conditions = append(conditions, fmt.Sprintf("runtime <= %s", args.add(*criteria.RuntimeMax)))
+	}
+
+	if criteria.CreatedAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at > %s", args.add(*criteria.CreatedAfter)))
+	}
+
+	if criteria.CreatedBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at < %s", args.add(*criteria.CreatedBefore)))
+	}
+
+	if criteria.MinRating != nil {
+		conditions = append(conditions, fmt.Sprintf("rating_average >= %s", args.add(*criteria.MinRating)))
+	}
+
+	if len(criteria.Statuses) > 0 {
+		conditions = append(conditions, fmt.Sprintf("status = ANY(%s::text[])", args.add(pq.Array(criteria.Statuses))))
+	}
+
+	if criteria.CreatedBy != nil {
+		conditions = append(conditions, fmt.Sprintf("created_by = %s", args.add(*criteria.CreatedBy)))
+	}
+
+	if criteria.PublishedOnly {
+		conditions = append(conditions, fmt.Sprintf("(status = %s OR created_by = %s)", args.add(StatusPublished), args.add(criteria.Viewer)))
+	}
+
+	if criteria.Person != nil {
+		credited := fmt.Sprintf("movie_credits.person_id = %s", args.add(*criteria.Person))
+
+		if criteria.Role != "" {
+			credited += fmt.Sprintf(" AND movie_credits.role = %s", args.add(criteria.Role))
+		}
+
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM movie_credits WHERE movie_credits.movie_id = movies.id AND %s)", credited))
+	}
+
+	return strings.Join(conditions, " AND ")
 }
 
-func (m *MovieModel) Insert(movie *Movie) error {
-	statement := `INSERT INTO movies (title, year, runtime, genres)
-                VALUES ($1, $2, $3, $4)
-                RETURNING id, created_at, version`
+func (criteria MovieCriteria) tsquery(args *queryArgs) string {
+	return fmt.Sprintf("websearch_to_tsquery('simple', %s)", args.add(criteria.Title))
+}
 
-	row := m.DB.QueryRow(statement, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres))
+// rank returns the expression used for sort=relevance: the best match among the
+// movie's original, translated and alternative titles.
+func (criteria MovieCriteria) rank(args *queryArgs) string {
+	if criteria.Fuzzy {
+		title := args.add(criteria.Title)
+		return fmt.Sprintf(`GREATEST(word_similarity(%[1]s, title), (
+					SELECT max(word_similarity(%[1]s, movie_titles.title)) FROM movie_titles WHERE movie_titles.movie_id = movies.id))`, title)
+	}
 
-	return row.Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	query := criteria.tsquery(args)
+	return fmt.Sprintf(`GREATEST(ts_rank(search_vector, %[1]s), (
+					SELECT max(ts_rank(movie_titles.search_vector, %[1]s)) FROM movie_titles WHERE movie_titles.movie_id = movies.id))`, query)
 }
 
-func (m *MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+// GetAll lists the movies matching the criteria. Any facets named are counted over
+// every matching movie, not just the current page, under the same timeout.
+func (m *MovieModel) GetAll(criteria MovieCriteria, filters Filters, facetNames []string) ([]*Movie, Metadata, Facets, error) {
+	args := queryArgs{}
+
+	keys := filters.sortKeys()
+	sortValueExprs := make([]string, len(keys))
+
+	for i := range keys {
+		switch keys[i].expr {
+		case "relevance":
+			// Relevance always lists the best matches first.
+			keys[i] = sortKey{expr: criteria.rank(&args), direction: "DESC"}
+		case "rating":
+			keys[i].expr = "rating_average"
+		}
+
+		sortValueExprs[i] = keys[i].expr + "::text"
+	}
+
+	columns, fields := movieSelection(filters.Fields)
+
+	headlineExpr := "''"
+	if criteria.Highlight && criteria.Title != "" && !criteria.Fuzzy {
+		headlineExpr = fmt.Sprintf("ts_headline('simple', title, %s, 'StartSel=<mark>, StopSel=</mark>')", criteria.tsquery(&args))
+	}
+
+	// Counting every matching row is what makes deep offset pages slow, so keyset
+	// pages skip the window function entirely.
+	totalExpr := "count(*) OVER()"
+	if filters.UseCursor {
+		totalExpr = "0"
+	}
+
 	statement := fmt.Sprintf(`
-				SELECT count(*) OVER(), id, title, year, runtime, created_at, genres, version 
+				SELECT %s, %s, %s, %s
                 FROM movies
-                WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-                AND (genres @> $2 OR $2 = '{}')
-                ORDER BY %s %s, id ASC
-				LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+                WHERE %s
+                AND %s
+                ORDER BY %s
+				LIMIT %s OFFSET %s`,
+		totalExpr, columns, headlineExpr, strings.Join(sortValueExprs, ", "),
+		criteria.where(&args),
+		filters.keysetCondition(keys, &args),
+		orderBy(keys, "id"),
+		args.add(filters.limit()), args.add(filters.offset()))
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, statement, title, pq.Array(genres), filters.limit(), filters.offset())
+	rows, err := m.DB.QueryContext(ctx, statement, args...)
 	if err != nil {
-		return nil, Metadata{}, err
+		return nil, Metadata{}, nil, err
 	}
 	defer rows.Close()
 
 	totalRecords := 0
 	movies := []*Movie{}
+	sortValues := [][]string{}
 
 	for rows.Next() {
 		var movie Movie
+		values := make([]string, len(keys))
 
-		err := rows.Scan(
-			&totalRecords,
-			&movie.ID,
-			&movie.Title,
-			&movie.Year,
-			&movie.Runtime,
-			&movie.CreatedAt,
-			pq.Array(&movie.Genres),
-			&movie.Version,
-		)
-		if err != nil {
-			return nil, Metadata{}, err
+		dest := []any{&totalRecords}
+		dest = append(dest, fields(&movie)...)
+		dest = append(dest, &movie.Headline)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, Metadata{}, nil, err
 		}
 
 		movies = append(movies, &movie)
+		sortValues = append(sortValues, values)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, Metadata{}, err
+		return nil, Metadata{}, nil, err
+	}
+
+	var facets Facets
+
+	if len(facetNames) > 0 {
+		facets, err = m.facets(ctx, criteria, facetNames)
+		if err != nil {
+			return nil, Metadata{}, nil, err
+		}
+	}
+
+	if filters.UseCursor {
+		// The extra row only tells us whether another page exists; the cursor points
+		// at the last row actually returned.
+		if len(movies) <= filters.PageSize {
+			return movies, calculateCursorMetadata(filters, nil, 0), facets, nil
+		}
+
+		movies = movies[:filters.PageSize]
+		last := len(movies) - 1
+
+		return movies, calculateCursorMetadata(filters, sortValues[last], movies[last].ID), facets, nil
 	}
 
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 
-	return movies, metadata, nil
+	return movies, metadata, facets, nil
+}
+
+// Export streams every movie matching the criteria, in id order, to fn as it is read
+// from the database cursor. The export stops at the first error returned by fn. It
+// runs for as long as ctx allows rather than under the usual query timeout.
+func (m *MovieModel) Export(ctx context.Context, criteria MovieCriteria, fn func(*Movie) error) error {
+	args := queryArgs{}
+
+	statement := fmt.Sprintf(`
+				SELECT %s
+                FROM movies
+                WHERE %s
+                ORDER BY id ASC`, movieColumns, criteria.where(&args))
+
+	rows, err := m.DB.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movie Movie
+
+		if err := rows.Scan(movieFields(&movie)...); err != nil {
+			return err
+		}
+
+		if err := fn(&movie); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Suggest returns up to limit movies whose title starts with prefix or closely
+// resembles it. Prefix matches score 1 and rank ahead of trigram similarity matches.
+// With publishedOnly, movies that aren't published are left out.
+func (m *MovieModel) Suggest(prefix string, limit int, publishedOnly bool) ([]*MovieSuggestion, error) {
+	statement := `SELECT id, title, year,
+					CASE WHEN title ILIKE $1 THEN 1 ELSE word_similarity($2, title) END AS score
+				FROM movies
+				WHERE (title ILIKE $1 OR $2 <% title)
+				AND deleted_at IS NULL
+				AND (status = 'published' OR NOT $4::boolean)
+				ORDER BY score DESC, title ASC, id ASC
+				LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	pattern := likeEscaper.Replace(prefix) + "%"
+
+	rows, err := m.DB.QueryContext(ctx, statement, pattern, prefix, limit, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*MovieSuggestion{}
+
+	for rows.Next() {
+		var suggestion MovieSuggestion
+
+		if err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year, &suggestion.Score); err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
 }
 
 func (m *MovieModel) Get(id int64) (*Movie, error) {
+	return m.GetFields(id, nil)
+}
+
+// GetFields reads only the named fields of the movie, or all of them when none are
+// named.
+func (m *MovieModel) GetFields(id int64, names []string) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	statement := `SELECT id, title, year, runtime, created_at, genres, version 
+	columns, fields := movieSelection(names)
+
+	statement := `SELECT ` + columns + `
                 FROM movies
-                WHERE id = $1`
+                WHERE id = $1 AND deleted_at IS NULL`
 
 	var movie Movie
 
@@ -117,7 +824,7 @@ func (m *MovieModel) Get(id int64) (*Movie, error) {
 	defer cancel()
 
 	row := m.DB.QueryRowContext(ctx, statement, id)
-	err := row.Scan(&movie.ID, &movie.Title, &movie.Year, &movie.Runtime, &movie.CreatedAt, pq.Array(&movie.Genres), &movie.Version)
+	err := row.Scan(fields(&movie)...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -130,13 +837,52 @@ func (m *MovieModel) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (m *MovieModel) Update(movie *Movie) error {
+// GetOwner returns the ID of the user owning the movie, or zero if it has no owner.
+// Movies in the trash are included, so that their owners can restore them.
+func (m *MovieModel) GetOwner(id int64) (int64, error) {
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	statement := `SELECT COALESCE(created_by, 0) FROM movies WHERE id = $1`
+
+	var owner int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, id).Scan(&owner)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return owner, nil
+}
+
+// Update saves the movie if it is still at movie.Version, and records the new
+// version as a revision edited by userID.
+func (m *MovieModel) Update(movie *Movie, userID int64) error {
 	statement := `UPDATE movies
-                SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1 
-                WHERE id = $5 AND version = $6
+                SET title = $1, year = $2, runtime = $3, genres = $4, poster_url = $5, poster_thumbnail_url = $6, version = version + 1
+                WHERE id = $7 AND version = $8 AND deleted_at IS NULL
                 RETURNING version`
 
-	row := m.DB.QueryRow(statement, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ID, movie.Version)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, statement, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres),
+		movie.PosterURL, movie.PosterThumbnailURL, movie.ID, movie.Version)
 	if err := row.Scan(&movie.Version); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -146,21 +892,209 @@ func (m *MovieModel) Update(movie *Movie) error {
 		}
 	}
 
-	return nil
+	if err := insertRevision(ctx, tx, movie, userID); err != nil {
+		return err
+	}
+
+	// Movies read back without their external IDs leave them untouched.
+	if movie.ExternalIDs != nil {
+		if err := replaceExternalIDs(ctx, tx, movie); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete moves a movie to the trash. It stays restorable until it is purged. A
+// non-zero version makes the delete conditional on the movie still being at that
+// version, returning ErrEditConflict otherwise.
+func (m *MovieModel) Delete(id int64, version int32) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	statement := `UPDATE movies
+                SET deleted_at = NOW()
+                WHERE id = $1 AND deleted_at IS NULL AND (version = $2 OR $2 = 0)`
+
+	err := m.execOne(statement, id, version)
+	if errors.Is(err, ErrRecordNotFound) && version != 0 {
+		return ErrEditConflict
+	}
+
+	return err
+}
+
+// GetTrash lists the movies that have been deleted but not yet purged. A non-zero
+// createdBy only lists the movies created by that user.
+func (m *MovieModel) GetTrash(filters Filters, createdBy int64) ([]*Movie, Metadata, error) {
+	statement := fmt.Sprintf(`
+				SELECT count(*) OVER(), %s, deleted_at
+                FROM movies
+                WHERE deleted_at IS NOT NULL
+                AND (created_by = $3 OR $3 = 0)
+                ORDER BY %s
+				LIMIT $1 OFFSET $2`, movieColumns, orderBy(filters.sortKeys(), "id"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, filters.limit(), filters.offset(), createdBy)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		dest := []any{&totalRecords}
+		dest = append(dest, movieFields(&movie)...)
+		dest = append(dest, &movie.DeletedAt)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
 }
 
-func (m *MovieModel) Delete(id int64) error {
+// Restore takes a movie back out of the trash.
+func (m *MovieModel) Restore(id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
+	statement := `UPDATE movies
+                SET deleted_at = NULL
+                WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	return m.execOne(statement, id)
+}
+
+// Purge permanently removes a movie, returning the URLs of its poster and thumbnail so
+// the files can be cleaned up. Only movies already in the trash can be purged.
+func (m *MovieModel) Purge(id int64) ([]string, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
 	statement := `DELETE FROM movies
-                WHERE id = $1`
+                WHERE id = $1 AND deleted_at IS NOT NULL
+                RETURNING poster_url, poster_thumbnail_url`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	var posterURL, thumbnailURL string
+
+	err := m.DB.QueryRowContext(ctx, statement, id).Scan(&posterURL, &thumbnailURL)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return []string{posterURL, thumbnailURL}, nil
+}
+
+// PurgeDeletedBefore permanently removes every movie that was moved to the trash
+// before cutoff, returning how many were removed and the URLs of their posters and
+// thumbnails.
+func (m *MovieModel) PurgeDeletedBefore(cutoff time.Time) (int64, []string, error) {
+	statement := `DELETE FROM movies
+                WHERE deleted_at < $1
+                RETURNING poster_url, poster_thumbnail_url`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, cutoff)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var removed int64
+	var posters []string
+
+	for rows.Next() {
+		var posterURL, thumbnailURL string
+
+		if err := rows.Scan(&posterURL, &thumbnailURL); err != nil {
+			return 0, nil, err
+		}
+
+		removed++
+		posters = append(posters, posterURL, thumbnailURL)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return removed, posters, nil
+}
+
+// UnusedPosters returns the URLs among urls that no movie, in the trash or not, uses
+// as its poster or thumbnail any more. Poster files are named by their content, so
+// several movies can share one.
+func (m *MovieModel) UnusedPosters(urls []string) ([]string, error) {
+	statement := `SELECT DISTINCT url FROM unnest($1::text[]) AS url
+                WHERE url <> ''
+                AND NOT EXISTS (SELECT 1 FROM movies WHERE poster_url = url OR poster_thumbnail_url = url)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, pq.Array(urls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var unused []string
+
+	for rows.Next() {
+		var url string
+
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+
+		unused = append(unused, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return unused, nil
+}
 
+// execOne runs a statement that is expected to affect exactly one row, returning
+// ErrRecordNotFound when it affected none.
+func (m *MovieModel) execOne(statement string, args ...any) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, statement, id)
+	result, err := m.DB.ExecContext(ctx, statement, args...)
 	if err != nil {
 		return err
 	}
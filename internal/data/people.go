@@ -0,0 +1,324 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateCredit = errors.New("duplicate credit")
+)
+
+// CreditRoles are the roles a person can be credited with on a movie.
+var CreditRoles = []string{"director", "writer", "actor"}
+
+type Person struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	BirthYear int32     `json:"birth_year,omitempty"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Credit links a person to a movie in a role. Character is only set for actors.
+type Credit struct {
+	ID        int64  `json:"id"`
+	MovieID   int64  `json:"movie_id"`
+	PersonID  int64  `json:"person_id"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	Character string `json:"character,omitempty"`
+}
+
+func ValidatePerson(val *validator.Validator, person *Person) {
+	val.Check(person.Name != "", "name", "must be provided")
+	val.Check(len(person.Name) <= 200, "name", "must not be more than 200 bytes long")
+
+	if person.BirthYear != 0 {
+		val.Check(person.BirthYear >= 1800, "birth_year", "must be greater than 1800")
+		val.Check(person.BirthYear <= int32(time.Now().Year()), "birth_year", "must not be in the future")
+	}
+}
+
+func ValidateCredit(val *validator.Validator, credit *Credit) {
+	val.Check(credit.PersonID > 0, "person_id", "must be provided")
+	val.Check(validator.PermittedValue(credit.Role, CreditRoles...), "role", "must be one of director, writer or actor")
+	val.Check(len(credit.Character) <= 200, "character", "must not be more than 200 bytes long")
+	val.Check(credit.Character == "" || credit.Role == "actor", "character", "can only be set for actors")
+}
+
+type PersonModel struct {
+	DB *sql.DB
+}
+
+func (m *PersonModel) Insert(person *Person) error {
+	statement := `INSERT INTO people (name, birth_year)
+				VALUES ($1, NULLIF($2::integer, 0))
+				RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, statement, person.Name, person.BirthYear).Scan(&person.ID, &person.CreatedAt, &person.Version)
+}
+
+func (m *PersonModel) Get(id int64) (*Person, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	statement := `SELECT id, name, COALESCE(birth_year, 0), version, created_at
+				FROM people
+				WHERE id = $1`
+
+	var person Person
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, id).Scan(&person.ID, &person.Name, &person.BirthYear, &person.Version, &person.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &person, nil
+}
+
+// GetAll lists people, optionally narrowed to names containing the given text.
+func (m *PersonModel) GetAll(name string, filters Filters) ([]*Person, Metadata, error) {
+	statement := fmt.Sprintf(`
+				SELECT count(*) OVER(), id, name, COALESCE(birth_year, 0), version, created_at
+				FROM people
+				WHERE name ILIKE '%%' || $1 || '%%'
+				ORDER BY %s
+				LIMIT $2 OFFSET $3`, orderBy(filters.sortKeys(), "id"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, likeEscaper.Replace(name), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	people := []*Person{}
+
+	for rows.Next() {
+		var person Person
+
+		err := rows.Scan(&totalRecords, &person.ID, &person.Name, &person.BirthYear, &person.Version, &person.CreatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		people = append(people, &person)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return people, metadata, nil
+}
+
+func (m *PersonModel) Update(person *Person) error {
+	statement := `UPDATE people
+				SET name = $1, birth_year = NULLIF($2::integer, 0), version = version + 1
+				WHERE id = $3 AND version = $4
+				RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, person.Name, person.BirthYear, person.ID, person.Version).Scan(&person.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the person along with all of their credits.
+func (m *PersonModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM people WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+type CreditModel struct {
+	DB *sql.DB
+}
+
+// GetAllForMovie lists a movie's credits, directors first, then writers, then actors.
+func (m *CreditModel) GetAllForMovie(movieID int64) ([]*Credit, error) {
+	statement := `SELECT movie_credits.id, movie_credits.movie_id, movie_credits.person_id, people.name,
+					movie_credits.role, movie_credits.character
+				FROM movie_credits
+				INNER JOIN people ON people.id = movie_credits.person_id
+				WHERE movie_credits.movie_id = $1
+				ORDER BY array_position($2::text[], movie_credits.role), movie_credits.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, movieID, pq.Array(CreditRoles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+
+		err := rows.Scan(&credit.ID, &credit.MovieID, &credit.PersonID, &credit.Name, &credit.Role, &credit.Character)
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, &credit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// GetAllForPerson lists a person's credits on the given movies, in the given role when
+// one is set, in the same order as GetAllForMovie.
+func (m *CreditModel) GetAllForPerson(personID int64, movieIDs []int64, role string) ([]*Credit, error) {
+	statement := `SELECT movie_credits.id, movie_credits.movie_id, movie_credits.person_id, people.name,
+					movie_credits.role, movie_credits.character
+				FROM movie_credits
+				INNER JOIN people ON people.id = movie_credits.person_id
+				WHERE movie_credits.person_id = $1
+				AND movie_credits.movie_id = ANY($2)
+				AND (movie_credits.role = $3 OR $3 = '')
+				ORDER BY array_position($4::text[], movie_credits.role), movie_credits.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, personID, pq.Array(movieIDs), role, pq.Array(CreditRoles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+
+		err := rows.Scan(&credit.ID, &credit.MovieID, &credit.PersonID, &credit.Name, &credit.Role, &credit.Character)
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, &credit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// Insert adds the credit and fills in the person's name. It returns ErrRecordNotFound
+// if the person doesn't exist.
+func (m *CreditModel) Insert(credit *Credit) error {
+	statement := `INSERT INTO movie_credits (movie_id, person_id, role, character)
+				SELECT $1, people.id, $3, $4
+				FROM people
+				WHERE people.id = $2
+				RETURNING id, (SELECT name FROM people WHERE id = $2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	row := m.DB.QueryRowContext(ctx, statement, credit.MovieID, credit.PersonID, credit.Role, strings.TrimSpace(credit.Character))
+
+	if err := row.Scan(&credit.ID, &credit.Name); err != nil {
+		var pqErr *pq.Error
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case errors.As(err, &pqErr) && pqErr.Code == "23505":
+			return ErrDuplicateCredit
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *CreditModel) Delete(movieID int64, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM movie_credits WHERE id = $1 AND movie_id = $2`, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// MovieStatuses holds every status of the publication workflow, in order.
+var MovieStatuses = []string{StatusDraft, StatusInReview, StatusPublished, StatusArchived}
+
+// statusTransitions lists the statuses a movie can move to from each status. A movie
+// in review can stay there to have its publication rescheduled.
+var statusTransitions = map[string][]string{
+	StatusDraft:     {StatusInReview},
+	StatusInReview:  {StatusInReview, StatusDraft, StatusPublished},
+	StatusPublished: {StatusArchived},
+	StatusArchived:  {StatusPublished},
+}
+
+// ValidateStatusChange checks that a movie in status from may move to status to. A
+// publish_at time schedules a movie in review to be published automatically, so it
+// is only accepted with the in_review status and must be in the future.
+func ValidateStatusChange(val *validator.Validator, from string, to string, publishAt *time.Time) {
+	val.Check(to != "", "status", "must be provided")
+	val.Check(to == "" || validator.PermittedValue(to, MovieStatuses...), "status", "must be one of draft, in_review, published or archived")
+
+	if val.Valid() {
+		val.Check(validator.PermittedValue(to, statusTransitions[from]...), "status", fmt.Sprintf("cannot change from %s to %s", from, to))
+	}
+
+	if publishAt != nil {
+		val.Check(to == StatusInReview, "publish_at", "can only be set for a movie in review")
+		val.Check(publishAt.After(time.Now()), "publish_at", "must be in the future")
+	}
+}
+
+// RequiresPublishPermission reports whether a status change takes the movies:publish
+// permission on top of movies:write: anything making a movie go live, taking it down
+// or scheduling it does.
+func RequiresPublishPermission(from string, to string, publishAt *time.Time) bool {
+	return publishAt != nil ||
+		validator.PermittedValue(from, StatusPublished, StatusArchived) ||
+		validator.PermittedValue(to, StatusPublished, StatusArchived)
+}
+
+// SetStatus saves the movie's status and publish_at time if it is still at
+// movie.Version, and records the new version as a revision edited by userID.
+func (m *MovieModel) SetStatus(movie *Movie, userID int64) error {
+	statement := `UPDATE movies
+				SET status = $1, publish_at = $2, version = version + 1
+				WHERE id = $3 AND version = $4 AND deleted_at IS NULL
+				RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, statement, movie.Status, movie.PublishAt, movie.ID, movie.Version).Scan(&movie.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if err := insertRevision(ctx, tx, movie, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PublishDue publishes every movie in review whose publish_at time has passed by now,
+// recording the new versions as revisions with no editor, and returns how many were
+// published.
+func (m *MovieModel) PublishDue(now time.Time) (int64, error) {
+	statement := `WITH published AS (
+					UPDATE movies
+					SET status = 'published', version = version + 1
+					WHERE status = 'in_review' AND publish_at <= $1 AND deleted_at IS NULL
+					RETURNING id, version, title, year, runtime, genres
+				), revisions AS (
+					INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, edited_by)
+					SELECT id, version, title, year, runtime, genres, NULL FROM published
+				)
+				SELECT count(*) FROM published`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	var published int64
+
+	if err := m.DB.QueryRowContext(ctx, statement, now).Scan(&published); err != nil {
+		return 0, err
+	}
+
+	return published, nil
+}
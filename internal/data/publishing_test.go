@@ -0,0 +1,131 @@
+package data
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+func TestValidateStatusChange(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		from      string
+		to        string
+		publishAt *time.Time
+		want      map[string]string
+	}{
+		{name: "submit draft", from: StatusDraft, to: StatusInReview},
+		{name: "send back to draft", from: StatusInReview, to: StatusDraft},
+		{name: "publish", from: StatusInReview, to: StatusPublished},
+		{name: "schedule", from: StatusInReview, to: StatusInReview, publishAt: &future},
+		{name: "schedule on submit", from: StatusDraft, to: StatusInReview, publishAt: &future},
+		{name: "archive", from: StatusPublished, to: StatusArchived},
+		{name: "republish", from: StatusArchived, to: StatusPublished},
+		{
+			name: "missing status",
+			from: StatusDraft,
+			want: map[string]string{"status": "must be provided"},
+		},
+		{
+			name: "unknown status",
+			from: StatusDraft,
+			to:   "deleted",
+			want: map[string]string{"status": "must be one of draft, in_review, published or archived"},
+		},
+		{
+			name: "publish draft",
+			from: StatusDraft,
+			to:   StatusPublished,
+			want: map[string]string{"status": "cannot change from draft to published"},
+		},
+		{
+			name: "archive draft",
+			from: StatusDraft,
+			to:   StatusArchived,
+			want: map[string]string{"status": "cannot change from draft to archived"},
+		},
+		{
+			name: "unpublish to draft",
+			from: StatusPublished,
+			to:   StatusDraft,
+			want: map[string]string{"status": "cannot change from published to draft"},
+		},
+		{
+			name: "republish published",
+			from: StatusPublished,
+			to:   StatusPublished,
+			want: map[string]string{"status": "cannot change from published to published"},
+		},
+		{
+			name:      "schedule in the past",
+			from:      StatusInReview,
+			to:        StatusInReview,
+			publishAt: &past,
+			want:      map[string]string{"publish_at": "must be in the future"},
+		},
+		{
+			name:      "schedule outside review",
+			from:      StatusInReview,
+			to:        StatusPublished,
+			publishAt: &future,
+			want:      map[string]string{"publish_at": "can only be set for a movie in review"},
+		},
+		{
+			name:      "invalid transition and schedule",
+			from:      StatusArchived,
+			to:        StatusDraft,
+			publishAt: &past,
+			want: map[string]string{
+				"status":     "cannot change from archived to draft",
+				"publish_at": "can only be set for a movie in review",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val := validator.New()
+			ValidateStatusChange(val, tt.from, tt.to, tt.publishAt)
+
+			want := tt.want
+			if want == nil {
+				want = map[string]string{}
+			}
+
+			if !maps.Equal(val.Errors, want) {
+				t.Errorf("got errors %v; want %v", val.Errors, want)
+			}
+		})
+	}
+}
+
+func TestRequiresPublishPermission(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		from      string
+		to        string
+		publishAt *time.Time
+		want      bool
+	}{
+		{from: StatusDraft, to: StatusInReview, want: false},
+		{from: StatusInReview, to: StatusDraft, want: false},
+		{from: StatusInReview, to: StatusInReview, publishAt: &future, want: true},
+		{from: StatusInReview, to: StatusPublished, want: true},
+		{from: StatusPublished, to: StatusArchived, want: true},
+		{from: StatusArchived, to: StatusPublished, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := RequiresPublishPermission(tt.from, tt.to, tt.publishAt); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
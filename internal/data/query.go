@@ -0,0 +1,19 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// likeEscaper escapes the LIKE wildcards in user input so it is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// queryArgs collects the positional arguments of a statement that is assembled at
+// runtime, so that optional clauses can be added without tracking $n by hand.
+type queryArgs []any
+
+// add appends value to the argument list and returns its placeholder.
+func (a *queryArgs) add(value any) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("$%d", len(*a))
+}
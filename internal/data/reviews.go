@@ -0,0 +1,232 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateReview = errors.New("duplicate review")
+)
+
+type Review struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movie_id"`
+	UserID    int64     `json:"user_id"`
+	Rating    int       `json:"rating"`
+	Body      string    `json:"body,omitempty"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ValidateReview(val *validator.Validator, review *Review) {
+	val.Check(review.Rating >= 1, "rating", "must be at least 1")
+	val.Check(review.Rating <= 10, "rating", "must be at most 10")
+
+	val.Check(len(review.Body) <= 10_000, "body", "must not be more than 10000 bytes long")
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+func (m *ReviewModel) Insert(review *Review) error {
+	statement := `INSERT INTO reviews (movie_id, user_id, rating, body)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id, created_at, updated_at, version`
+
+	return m.withRatingRefresh(review.MovieID, func(ctx context.Context, tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, statement, review.MovieID, review.UserID, review.Rating, review.Body)
+
+		err := row.Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt, &review.Version)
+		if err != nil {
+			var pqErr *pq.Error
+
+			switch {
+			case errors.As(err, &pqErr) && pqErr.Code == "23505":
+				return ErrDuplicateReview
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *ReviewModel) Get(movieID int64, id int64) (*Review, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	statement := `SELECT id, movie_id, user_id, rating, body, version, created_at, updated_at
+				FROM reviews
+				WHERE id = $1 AND movie_id = $2`
+
+	var review Review
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	row := m.DB.QueryRowContext(ctx, statement, id, movieID)
+	err := row.Scan(&review.ID, &review.MovieID, &review.UserID, &review.Rating, &review.Body, &review.Version, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+func (m *ReviewModel) GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error) {
+	statement := fmt.Sprintf(`
+				SELECT count(*) OVER(), id, movie_id, user_id, rating, body, version, created_at, updated_at
+				FROM reviews
+				WHERE movie_id = $1
+				ORDER BY %s
+				LIMIT $2 OFFSET $3`, orderBy(filters.sortKeys(), "id"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+
+		err := rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.MovieID,
+			&review.UserID,
+			&review.Rating,
+			&review.Body,
+			&review.Version,
+			&review.CreatedAt,
+			&review.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reviews, metadata, nil
+}
+
+func (m *ReviewModel) Update(review *Review) error {
+	statement := `UPDATE reviews
+				SET rating = $1, body = $2, updated_at = NOW(), version = version + 1
+				WHERE id = $3 AND version = $4
+				RETURNING updated_at, version`
+
+	return m.withRatingRefresh(review.MovieID, func(ctx context.Context, tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, statement, review.Rating, review.Body, review.ID, review.Version)
+
+		if err := row.Scan(&review.UpdatedAt, &review.Version); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrEditConflict
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *ReviewModel) Delete(review *Review) error {
+	statement := `DELETE FROM reviews
+				WHERE id = $1`
+
+	return m.withRatingRefresh(review.MovieID, func(ctx context.Context, tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, statement, review.ID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+// withRatingRefresh runs fn in a transaction and then recomputes the movie's rating
+// average and count. The movie row is locked first so that concurrent reviews of the
+// same movie can't each miss the other's change.
+func (m *ReviewModel) withRatingRefresh(movieID int64, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+
+	err = tx.QueryRowContext(ctx, `SELECT id FROM movies WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, movieID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := refreshRating(ctx, tx, movieID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// refreshRating recomputes the movie's rating average and count from its reviews.
+func refreshRating(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	statement := `UPDATE movies
+				SET rating_count = stats.count, rating_average = stats.average
+				FROM (SELECT count(*) AS count, COALESCE(avg(rating), 0) AS average FROM reviews WHERE movie_id = $1) AS stats
+				WHERE movies.id = $1`
+
+	_, err := tx.ExecContext(ctx, statement, movieID)
+	return err
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieRevision is a snapshot of a movie as it was at a given version.
+type MovieRevision struct {
+	MovieID   int64     `json:"movie_id"`
+	Version   int32     `json:"version"`
+	Title     string    `json:"title"`
+	Year      int32     `json:"year"`
+	Runtime   Runtime   `json:"runtime"`
+	Genres    []string  `json:"genres"`
+	EditedBy  *int64    `json:"edited_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// RuntimeFormat is the format Runtime is written in, "<n> mins" when empty.
+	RuntimeFormat RuntimeFormat `json:"-"`
+}
+
+func (revision MovieRevision) MarshalJSON() ([]byte, error) {
+	type plainRevision MovieRevision
+
+	return json.Marshal(struct {
+		plainRevision
+		Runtime FormattedRuntime `json:"runtime"`
+	}{plainRevision(revision), FormattedRuntime{revision.Runtime, revision.RuntimeFormat}})
+}
+
+// FieldChange describes one field that differs between two revisions.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// DiffRevisions lists the fields that changed going from one revision to another. A
+// changed runtime is written in the RuntimeFormat of each revision.
+func DiffRevisions(from, to *MovieRevision) []FieldChange {
+	changes := []FieldChange{}
+
+	if from.Title != to.Title {
+		changes = append(changes, FieldChange{Field: "title", From: from.Title, To: to.Title})
+	}
+
+	if from.Year != to.Year {
+		changes = append(changes, FieldChange{Field: "year", From: from.Year, To: to.Year})
+	}
+
+	if from.Runtime != to.Runtime {
+		changes = append(changes, FieldChange{
+			Field: "runtime",
+			From:  FormattedRuntime{from.Runtime, from.RuntimeFormat},
+			To:    FormattedRuntime{to.Runtime, to.RuntimeFormat},
+		})
+	}
+
+	if !slices.Equal(from.Genres, to.Genres) {
+		changes = append(changes, FieldChange{Field: "genres", From: from.Genres, To: to.Genres})
+	}
+
+	return changes
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// insertRevision records the current state of movie as a revision. It runs inside the
+// caller's transaction so the snapshot is only kept if the write itself commits. A
+// zero userID records the revision without an editor.
+func insertRevision(ctx context.Context, tx *sql.Tx, movie *Movie, userID int64) error {
+	statement := `INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, edited_by)
+				VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7::bigint, 0))`
+
+	_, err := tx.ExecContext(ctx, statement, movie.ID, movie.Version, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), userID)
+	return err
+}
+
+func (m *RevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*MovieRevision, Metadata, error) {
+	statement := fmt.Sprintf(`
+				SELECT count(*) OVER(), movie_id, version, title, year, runtime, genres, edited_by, created_at
+				FROM movie_revisions
+				WHERE movie_id = $1
+				ORDER BY %s
+				LIMIT $2 OFFSET $3`, orderBy(filters.sortKeys(), "version"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*MovieRevision{}
+
+	for rows.Next() {
+		var revision MovieRevision
+
+		err := rows.Scan(
+			&totalRecords,
+			&revision.MovieID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Year,
+			&revision.Runtime,
+			pq.Array(&revision.Genres),
+			&revision.EditedBy,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
+
+func (m *RevisionModel) Get(movieID int64, version int32) (*MovieRevision, error) {
+	statement := `SELECT movie_id, version, title, year, runtime, genres, edited_by, created_at
+				FROM movie_revisions
+				WHERE movie_id = $1 AND version = $2`
+
+	var revision MovieRevision
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	row := m.DB.QueryRowContext(ctx, statement, movieID, version)
+	err := row.Scan(
+		&revision.MovieID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Year,
+		&revision.Runtime,
+		pq.Array(&revision.Genres),
+		&revision.EditedBy,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
@@ -3,40 +3,162 @@ package data
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
-var ErrInvalidRuntimeFormat = errors.New("invalid runtime format")
+var ErrInvalidRuntimeFormat = errors.New(`invalid runtime format: use a number of minutes, "<n> mins", "<h>h <m>m", "<n>s" or an ISO 8601 duration such as "PT1H42M"`)
 
 type Runtime int32
 
+// RuntimeFormat selects how runtimes are written in responses.
+type RuntimeFormat string
+
+const (
+	RuntimeFormatMins    RuntimeFormat = "mins"    // "102 mins"
+	RuntimeFormatHM      RuntimeFormat = "hm"      // "1h 42m"
+	RuntimeFormatISO8601 RuntimeFormat = "iso8601" // "PT1H42M"
+	RuntimeFormatSeconds RuntimeFormat = "seconds" // "6120s"
+)
+
+// RuntimeFormats holds every output format a client can ask for.
+var RuntimeFormats = []RuntimeFormat{RuntimeFormatMins, RuntimeFormatHM, RuntimeFormatISO8601, RuntimeFormatSeconds}
+
+var (
+	minsRegex    = regexp.MustCompile(`^(\d+) ?mins?$`)
+	hmRegex      = regexp.MustCompile(`^(?:(\d+)h)? ?(?:(\d+)m)?$`)
+	secondsRegex = regexp.MustCompile(`^(\d+)s$`)
+	iso8601Regex = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+)
+
+// MarshalJSON writes the "<n> mins" form. Runtimes are written in the other formats
+// through FormattedRuntime, which movies, revisions and diary stats use for the format
+// set in their RuntimeFormat field.
 func (r Runtime) MarshalJSON() ([]byte, error) {
-	jsonValue := fmt.Sprintf("%d mins", r)
+	return r.Format(RuntimeFormatMins), nil
+}
+
+// Format returns the runtime as a JSON value in the given format.
+func (r Runtime) Format(format RuntimeFormat) []byte {
+	return []byte(strconv.Quote(r.Text(format)))
+}
+
+// Text returns the runtime in the given format, defaulting to "<n> mins". Every
+// format reads back through UnmarshalJSON as the same runtime.
+func (r Runtime) Text(format RuntimeFormat) string {
+	hours, minutes := r/60, r%60
+
+	switch format {
+	case RuntimeFormatHM:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("%dm", minutes)
+		case minutes == 0:
+			return fmt.Sprintf("%dh", hours)
+		default:
+			return fmt.Sprintf("%dh %dm", hours, minutes)
+		}
+
+	case RuntimeFormatISO8601:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("PT%dM", minutes)
+		case minutes == 0:
+			return fmt.Sprintf("PT%dH", hours)
+		default:
+			return fmt.Sprintf("PT%dH%dM", hours, minutes)
+		}
+
+	case RuntimeFormatSeconds:
+		return fmt.Sprintf("%ds", int64(r)*60)
+
+	default:
+		return fmt.Sprintf("%d mins", r)
+	}
+}
 
-	quotedValue := strconv.Quote(jsonValue)
+// FormattedRuntime is a runtime that is written in a chosen format.
+type FormattedRuntime struct {
+	Runtime Runtime
+	Format  RuntimeFormat
+}
+
+func (r FormattedRuntime) MarshalJSON() ([]byte, error) {
+	return r.Runtime.Format(r.Format), nil
+}
+
+// UnmarshalJSON accepts a runtime in any of the formats Runtime does.
+func (r *FormattedRuntime) UnmarshalJSON(value []byte) error {
+	return r.Runtime.UnmarshalJSON(value)
+}
+
+// formatted returns the runtime in the given format, or nil for a zero runtime, so
+// that fields marked omitempty stay empty.
+func (r Runtime) formatted(format RuntimeFormat) *FormattedRuntime {
+	if r == 0 {
+		return nil
+	}
 
-	return []byte(quotedValue), nil
+	return &FormattedRuntime{Runtime: r, Format: format}
 }
 
+// UnmarshalJSON accepts a bare number of minutes, "<n> mins", "<h>h <m>m" (either
+// part may be left out), a whole minutes' worth of seconds as "<n>s", or an ISO 8601
+// duration made of hours, minutes and whole minutes' worth of seconds.
 func (r *Runtime) UnmarshalJSON(value []byte) error {
+	if minutes, err := strconv.ParseInt(string(value), 10, 32); err == nil {
+		*r = Runtime(minutes)
+		return nil
+	}
+
 	unquoteValue, err := strconv.Unquote(string(value))
 	if err != nil {
 		return ErrInvalidRuntimeFormat
 	}
 
-	parts := strings.Split(unquoteValue, " ")
+	unquoteValue = strings.TrimSpace(unquoteValue)
+
+	var hours, minutes, seconds string
 
-	if len(parts) != 2 || parts[1] != "mins" {
+	switch {
+	case minsRegex.MatchString(unquoteValue):
+		minutes = minsRegex.FindStringSubmatch(unquoteValue)[1]
+	case unquoteValue != "" && hmRegex.MatchString(unquoteValue):
+		parts := hmRegex.FindStringSubmatch(unquoteValue)
+		hours, minutes = parts[1], parts[2]
+	case secondsRegex.MatchString(unquoteValue):
+		seconds = secondsRegex.FindStringSubmatch(unquoteValue)[1]
+	case unquoteValue != "PT" && iso8601Regex.MatchString(unquoteValue):
+		parts := iso8601Regex.FindStringSubmatch(unquoteValue)
+		hours, minutes, seconds = parts[1], parts[2], parts[3]
+	default:
 		return ErrInvalidRuntimeFormat
 	}
 
-	idx, err := strconv.Atoi(parts[0])
-	if err != nil {
+	var total int64
+
+	for _, part := range []struct {
+		value   string
+		seconds int
+	}{{hours, 3600}, {minutes, 60}, {seconds, 1}} {
+		if part.value == "" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(part.value, 10, 32)
+		if err != nil {
+			return ErrInvalidRuntimeFormat
+		}
+
+		total += n * int64(part.seconds)
+	}
+
+	if total%60 != 0 || total/60 > 1<<31-1 {
 		return ErrInvalidRuntimeFormat
 	}
 
-	*r = Runtime(idx)
+	*r = Runtime(total / 60)
 
 	return nil
 }
@@ -0,0 +1,199 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRuntimeFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		runtime Runtime
+		want    map[RuntimeFormat]string
+	}{
+		{
+			runtime: 102,
+			want: map[RuntimeFormat]string{
+				RuntimeFormatMins:    `"102 mins"`,
+				RuntimeFormatHM:      `"1h 42m"`,
+				RuntimeFormatISO8601: `"PT1H42M"`,
+				RuntimeFormatSeconds: `"6120s"`,
+			},
+		},
+		{
+			runtime: 120,
+			want: map[RuntimeFormat]string{
+				RuntimeFormatMins:    `"120 mins"`,
+				RuntimeFormatHM:      `"2h"`,
+				RuntimeFormatISO8601: `"PT2H"`,
+				RuntimeFormatSeconds: `"7200s"`,
+			},
+		},
+		{
+			runtime: 45,
+			want: map[RuntimeFormat]string{
+				RuntimeFormatMins:    `"45 mins"`,
+				RuntimeFormatHM:      `"45m"`,
+				RuntimeFormatISO8601: `"PT45M"`,
+				RuntimeFormatSeconds: `"2700s"`,
+			},
+		},
+		{
+			runtime: 1,
+			want: map[RuntimeFormat]string{
+				RuntimeFormatMins:    `"1 mins"`,
+				RuntimeFormatHM:      `"1m"`,
+				RuntimeFormatISO8601: `"PT1M"`,
+				RuntimeFormatSeconds: `"60s"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range RuntimeFormats {
+			t.Run(tt.want[RuntimeFormatMins]+"/"+string(format), func(t *testing.T) {
+				got := string(tt.runtime.Format(format))
+				if got != tt.want[format] {
+					t.Errorf("Format(%q) = %s; want %s", format, got, tt.want[format])
+				}
+
+				var parsed Runtime
+				if err := parsed.UnmarshalJSON([]byte(got)); err != nil {
+					t.Fatalf("UnmarshalJSON(%s): %v", got, err)
+				}
+
+				if parsed != tt.runtime {
+					t.Errorf("UnmarshalJSON(%s) = %d; want %d", got, parsed, tt.runtime)
+				}
+			})
+		}
+	}
+}
+
+func TestRuntimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Runtime
+		wantErr bool
+	}{
+		{value: `102`, want: 102},
+		{value: `"102 mins"`, want: 102},
+		{value: `"102mins"`, want: 102},
+		{value: `"1 min"`, want: 1},
+		{value: `"1h42m"`, want: 102},
+		{value: `" 1h 42m "`, want: 102},
+		{value: `"2h"`, want: 120},
+		{value: `"PT1H42M"`, want: 102},
+		{value: `"PT90M"`, want: 90},
+		{value: `"PT1H30M60S"`, want: 91},
+		{value: `"PT6120S"`, want: 102},
+		{value: `"6120s"`, want: 102},
+		{value: `"6130s"`, wantErr: true},
+		{value: `"PT30S"`, wantErr: true},
+		{value: `"PT"`, wantErr: true},
+		{value: `""`, wantErr: true},
+		{value: `"102"`, wantErr: true},
+		{value: `"1d"`, wantErr: true},
+		{value: `"P1D"`, wantErr: true},
+		{value: `102.5`, wantErr: true},
+		{value: `"99999999999 mins"`, wantErr: true},
+		{value: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var got Runtime
+
+			err := got.UnmarshalJSON([]byte(tt.value))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRuntimeFormat) {
+					t.Errorf("got %d, %v; want ErrInvalidRuntimeFormat", got, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntimeFormatField(t *testing.T) {
+	revisions := []*MovieRevision{{Runtime: 90, RuntimeFormat: RuntimeFormatHM}, {Runtime: 102, RuntimeFormat: RuntimeFormatHM}}
+
+	tests := []struct {
+		name    string
+		value   any
+		want    string
+		notWant string
+	}{
+		{
+			name:  "movie",
+			value: &Movie{Title: "90 mins", Runtime: 90, RuntimeFormat: RuntimeFormatHM},
+			want:  `"runtime":"1h 30m"`,
+		},
+		{
+			name:  "default format",
+			value: &Movie{Runtime: 90},
+			want:  `"runtime":"90 mins"`,
+		},
+		{
+			name:  "similar movie",
+			value: &SimilarMovie{Movie: &Movie{Runtime: 90, RuntimeFormat: RuntimeFormatHM}},
+			want:  `"runtime":"1h 30m"`,
+		},
+		{
+			name:  "sparse fieldset",
+			value: (&Movie{Runtime: 90, RuntimeFormat: RuntimeFormatHM}).Project([]string{"runtime"}),
+			want:  `"runtime":"1h 30m"`,
+		},
+		{
+			name:  "revision",
+			value: revisions[0],
+			want:  `"runtime":"1h 30m"`,
+		},
+		{
+			name:  "revision diff",
+			value: DiffRevisions(revisions[0], revisions[1]),
+			want:  `"from":"1h 30m","to":"1h 42m"`,
+		},
+		{
+			name:  "diary stats",
+			value: &DiaryStats{TotalRuntime: 90, RuntimeFormat: RuntimeFormatHM},
+			want:  `"total_runtime":"1h 30m"`,
+		},
+		{
+			name:    "zero runtime",
+			value:   &Movie{Title: "x", RuntimeFormat: RuntimeFormatHM},
+			want:    `"title":"x"`,
+			notWant: `"runtime"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(string(js), tt.want) {
+				t.Errorf("got %s; want it to contain %s", js, tt.want)
+			}
+
+			if tt.notWant != "" && strings.Contains(string(js), tt.notWant) {
+				t.Errorf("got %s; want it not to contain %s", js, tt.notWant)
+			}
+
+			if strings.Contains(string(js), "RuntimeFormat") {
+				t.Errorf("got %s; want the format itself left out", js)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SimilarityWeights set how much genre overlap, year proximity and runtime proximity
+// each contribute to a similarity score.
+type SimilarityWeights struct {
+	Genres  float64
+	Year    float64
+	Runtime float64
+}
+
+// SimilarMovie is a movie together with how similar it is to the one asked about,
+// from 0 to 1.
+type SimilarMovie struct {
+	*Movie
+	Similarity float64 `json:"similarity"`
+}
+
+// MarshalJSON writes the similarity alongside the movie's own fields, which the
+// MarshalJSON method promoted from Movie would leave out.
+func (similar SimilarMovie) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		movieJSON
+		Similarity float64 `json:"similarity"`
+	}{similar.Movie.json(), similar.Similarity})
+}
+
+// GetSimilar ranks the other movies by their similarity to the movie with the given
+// id. Only movies sharing at least one genre are candidates, which lets the genres
+// GIN index narrow them down before any scoring. Genre overlap is the Jaccard index of
+// the two genre sets; year and runtime proximity fall off with the distance, halving
+// at 10 years and 30 minutes apart. The weighted sum is divided by the total weight.
+// With publishedOnly, movies that aren't published are left out unless viewer created
+// them.
+func (m *MovieModel) GetSimilar(id int64, weights SimilarityWeights, filters Filters, publishedOnly bool, viewer int64) ([]*SimilarMovie, Metadata, error) {
+	statement := fmt.Sprintf(`
+				WITH target AS (
+					SELECT id, genres, year, runtime
+					FROM movies
+					WHERE id = $1 AND deleted_at IS NULL
+				)
+				SELECT count(*) OVER(), %s,
+					($2::float8 * cardinality(ARRAY(SELECT unnest(movies.genres) INTERSECT SELECT unnest(target.genres)))
+						/ cardinality(ARRAY(SELECT unnest(movies.genres) UNION SELECT unnest(target.genres)))
+					+ $3::float8 / (1 + abs(movies.year - target.year) / 10.0)
+					+ $4::float8 / (1 + abs(movies.runtime - target.runtime) / 30.0))
+					/ ($2::float8 + $3::float8 + $4::float8) AS similarity
+				FROM movies, target
+				WHERE movies.genres && target.genres
+				AND movies.id <> target.id
+				AND movies.deleted_at IS NULL
+				AND (movies.status = 'published' OR movies.created_by = $8 OR NOT $7::boolean)
+				ORDER BY similarity DESC, movies.id ASC
+				LIMIT $5 OFFSET $6`, movieColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, id, weights.Genres, weights.Year, weights.Runtime, filters.limit(), filters.offset(), publishedOnly, viewer)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*SimilarMovie{}
+
+	for rows.Next() {
+		similar := SimilarMovie{Movie: &Movie{}}
+
+		dest := []any{&totalRecords}
+		dest = append(dest, movieFields(similar.Movie)...)
+		dest = append(dest, &similar.Similarity)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &similar)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
+}
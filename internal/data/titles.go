@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sparrowsl/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateTranslation = errors.New("duplicate translation")
+)
+
+const (
+	TitleTranslation = "translation"
+	TitleAlternative = "alternative"
+)
+
+// LocaleRegex matches BCP 47 style language tags such as "fr" or "pt-br".
+var LocaleRegex = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// MovieTitle is a title the movie is known by besides its original one. A movie has
+// at most one translation per locale, shown to clients asking for that locale, and
+// any number of alternative titles, which only help searches find it.
+type MovieTitle struct {
+	ID      int64  `json:"id"`
+	MovieID int64  `json:"movie_id"`
+	Kind    string `json:"kind"`
+	Locale  string `json:"locale,omitempty"`
+	Title   string `json:"title"`
+}
+
+func ValidateMovieTitle(val *validator.Validator, title *MovieTitle) {
+	val.Check(validator.PermittedValue(title.Kind, TitleTranslation, TitleAlternative), "kind", "must be translation or alternative")
+
+	val.Check(title.Title != "", "title", "must be provided")
+	val.Check(len(title.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	val.Check(title.Locale != "" || title.Kind != TitleTranslation, "locale", "must be provided for a translation")
+	val.Check(title.Locale == "" || validator.Matches(title.Locale, LocaleRegex), "locale", "must be a valid language tag")
+}
+
+type MovieTitleModel struct {
+	DB *sql.DB
+}
+
+func (m *MovieTitleModel) GetAllForMovie(movieID int64) ([]*MovieTitle, error) {
+	statement := `SELECT id, movie_id, kind, locale, title
+				FROM movie_titles
+				WHERE movie_id = $1
+				ORDER BY kind DESC, locale, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	titles := []*MovieTitle{}
+
+	for rows.Next() {
+		var title MovieTitle
+
+		if err := rows.Scan(&title.ID, &title.MovieID, &title.Kind, &title.Locale, &title.Title); err != nil {
+			return nil, err
+		}
+
+		titles = append(titles, &title)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return titles, nil
+}
+
+func (m *MovieTitleModel) Get(movieID int64, id int64) (*MovieTitle, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	statement := `SELECT id, movie_id, kind, locale, title
+				FROM movie_titles
+				WHERE id = $1 AND movie_id = $2`
+
+	var title MovieTitle
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, id, movieID).Scan(&title.ID, &title.MovieID, &title.Kind, &title.Locale, &title.Title)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &title, nil
+}
+
+func (m *MovieTitleModel) Insert(title *MovieTitle) error {
+	statement := `INSERT INTO movie_titles (movie_id, kind, locale, title)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, statement, title.MovieID, title.Kind, title.Locale, title.Title).Scan(&title.ID)
+	return titleError(err)
+}
+
+func (m *MovieTitleModel) Update(title *MovieTitle) error {
+	statement := `UPDATE movie_titles
+				SET kind = $1, locale = $2, title = $3
+				WHERE id = $4 AND movie_id = $5`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, statement, title.Kind, title.Locale, title.Title, title.ID, title.MovieID)
+	if err != nil {
+		return titleError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *MovieTitleModel) Delete(movieID int64, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM movie_titles WHERE id = $1 AND movie_id = $2`, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Localize replaces each movie's title with its translation for the first of the
+// locales, in order of preference, that it has one for. The original title is kept
+// in OriginalTitle. Movies without a matching translation are left alone.
+func (m *MovieTitleModel) Localize(movies []*Movie, locales []string) error {
+	if len(movies) == 0 || len(locales) == 0 {
+		return nil
+	}
+
+	statement := `SELECT DISTINCT ON (movie_id) movie_id, locale, title
+				FROM movie_titles
+				WHERE movie_id = ANY($1) AND kind = 'translation' AND locale = ANY($2)
+				ORDER BY movie_id, array_position($2, locale)`
+
+	ids := make([]int64, len(movies))
+	byID := make(map[int64][]*Movie, len(movies))
+
+	for i, movie := range movies {
+		ids[i] = movie.ID
+		byID[movie.ID] = append(byID[movie.ID], movie)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, pq.Array(ids), pq.Array(locales))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var locale, title string
+
+		if err := rows.Scan(&id, &locale, &title); err != nil {
+			return err
+		}
+
+		for _, movie := range byID[id] {
+			movie.OriginalTitle = movie.Title
+			movie.Title = title
+			movie.TitleLocale = locale
+		}
+	}
+
+	return rows.Err()
+}
+
+// titleError maps a second translation for the same locale to ErrDuplicateTranslation.
+func titleError(err error) error {
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateTranslation
+	}
+
+	return err
+}
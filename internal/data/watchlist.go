@@ -0,0 +1,199 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateWatchlistItem = errors.New("duplicate watchlist item")
+)
+
+type WatchlistItem struct {
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+	Movie    *Movie    `json:"movie"`
+}
+
+type WatchlistModel struct {
+	DB *sql.DB
+}
+
+// GetAllForUser lists the user's watchlist in position order. Movies in the trash are
+// left out, and with publishedOnly so are movies that aren't published, unless the
+// user created them.
+func (m *WatchlistModel) GetAllForUser(userID int64, filters Filters, publishedOnly bool) ([]*WatchlistItem, Metadata, error) {
+	statement := `SELECT count(*) OVER(), watchlist_items.position, watchlist_items.added_at, ` + movieColumns + `
+				FROM watchlist_items
+				INNER JOIN movies ON movies.id = watchlist_items.movie_id
+				WHERE watchlist_items.user_id = $1 AND movies.deleted_at IS NULL
+				AND (movies.status = 'published' OR movies.created_by = $1 OR NOT $4::boolean)
+				ORDER BY watchlist_items.position ASC
+				LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID, filters.limit(), filters.offset(), publishedOnly)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	items := []*WatchlistItem{}
+
+	for rows.Next() {
+		item := WatchlistItem{Movie: &Movie{}}
+
+		dest := []any{&totalRecords, &item.Position, &item.AddedAt}
+		dest = append(dest, movieFields(item.Movie)...)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return items, metadata, nil
+}
+
+// Add puts a movie on the user's watchlist at the given position, moving later
+// items down. A position of zero, or past the end, appends the movie.
+func (m *WatchlistModel) Add(userID int64, movieID int64, position int) error {
+	return m.reorder(userID, func(ctx context.Context, tx *sql.Tx, count int) error {
+		if position < 1 || position > count+1 {
+			position = count + 1
+		}
+
+		statement := `UPDATE watchlist_items SET position = position + 1
+					WHERE user_id = $1 AND position >= $2`
+
+		if _, err := tx.ExecContext(ctx, statement, userID, position); err != nil {
+			return err
+		}
+
+		statement = `INSERT INTO watchlist_items (user_id, movie_id, position)
+					VALUES ($1, $2, $3)`
+
+		if _, err := tx.ExecContext(ctx, statement, userID, movieID, position); err != nil {
+			var pqErr *pq.Error
+
+			switch {
+			case errors.As(err, &pqErr) && pqErr.Code == "23505":
+				return ErrDuplicateWatchlistItem
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Move changes the position of a movie already on the watchlist, shifting the items
+// in between. Positions past the end move it to the end.
+func (m *WatchlistModel) Move(userID int64, movieID int64, position int) error {
+	return m.reorder(userID, func(ctx context.Context, tx *sql.Tx, count int) error {
+		var current int
+
+		statement := `SELECT position FROM watchlist_items
+					WHERE user_id = $1 AND movie_id = $2`
+
+		err := tx.QueryRowContext(ctx, statement, userID, movieID).Scan(&current)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+
+		statement = `UPDATE watchlist_items
+					SET position = CASE
+						WHEN movie_id = $2 THEN $4::integer
+						WHEN $4::integer < $3::integer THEN position + 1
+						ELSE position - 1
+					END
+					WHERE user_id = $1 AND position BETWEEN least($3::integer, $4::integer) AND greatest($3::integer, $4::integer)`
+
+		_, err = tx.ExecContext(ctx, statement, userID, movieID, current, min(position, count))
+		return err
+	})
+}
+
+func (m *WatchlistModel) Remove(userID int64, movieID int64) error {
+	return m.reorder(userID, func(ctx context.Context, tx *sql.Tx, count int) error {
+		return removeItem(ctx, tx, userID, movieID)
+	})
+}
+
+// removeItem deletes a watchlist item and closes the gap it leaves.
+func removeItem(ctx context.Context, tx *sql.Tx, userID int64, movieID int64) error {
+	var position int
+
+	statement := `DELETE FROM watchlist_items
+				WHERE user_id = $1 AND movie_id = $2
+				RETURNING position`
+
+	err := tx.QueryRowContext(ctx, statement, userID, movieID).Scan(&position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	statement = `UPDATE watchlist_items SET position = position - 1
+				WHERE user_id = $1 AND position > $2`
+
+	_, err = tx.ExecContext(ctx, statement, userID, position)
+	return err
+}
+
+// reorder runs fn in a transaction with the user's watchlist locked, passing it the
+// current number of items, so that concurrent changes can't leave duplicate or
+// missing positions.
+func (m *WatchlistModel) reorder(userID int64, fn func(ctx context.Context, tx *sql.Tx, count int) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the user row rather than the items, which also serialises adding to an
+	// empty watchlist.
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return err
+	}
+
+	var count int
+
+	err = tx.QueryRowContext(ctx, `SELECT count(*) FROM watchlist_items WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(ctx, tx, count); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
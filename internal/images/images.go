@@ -0,0 +1,120 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrInvalidImage      = errors.New("invalid image")
+)
+
+// extensions maps the content types accepted for uploads to their file extensions.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type Image struct {
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+
+	data []byte
+}
+
+// Sniff identifies an uploaded image from its content, ignoring whatever name or
+// content type the client sent, and checks that its header is well formed.
+func Sniff(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+
+	extension, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width < 1 || config.Height < 1 {
+		return nil, ErrInvalidImage
+	}
+
+	return &Image{
+		ContentType: contentType,
+		Extension:   extension,
+		Width:       config.Width,
+		Height:      config.Height,
+		data:        data,
+	}, nil
+}
+
+// Thumbnail returns a JPEG of the image scaled down to the given width, keeping its
+// aspect ratio. Images already narrower are re-encoded at their own size.
+func (img *Image) Thumbnail(width int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(img.data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	if width > img.Width {
+		width = img.Width
+	}
+
+	height := max(1, img.Height*width/img.Width)
+
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, scale(src, width, height), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// scale resizes src by averaging the source pixels covered by each destination pixel,
+// which is cheap and gives clean results when shrinking. Transparent parts of src are
+// laid over white, since JPEG can't keep them and would otherwise show them as black.
+func scale(src image.Image, width int, height int) *image.RGBA {
+	bounds := src.Bounds()
+
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, max((y+1)*srcH/height, y*srcH/height+1)
+
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, max((x+1)*srcW/width, x*srcW/width+1)
+
+			var r, g, b, a, n int
+
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(p[0]), g+int(p[1]), b+int(p[2]), a+int(p[3])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,59 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestThumbnailTransparency(t *testing.T) {
+	tests := []struct {
+		name string
+		fill color.Color
+		want color.Gray
+	}{
+		{name: "transparent", fill: color.NRGBA{}, want: color.Gray{Y: 255}},
+		{name: "half transparent black", fill: color.NRGBA{A: 128}, want: color.Gray{Y: 127}},
+		{name: "opaque black", fill: color.NRGBA{A: 255}, want: color.Gray{Y: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, 40, 60))
+			draw.Draw(src, src.Bounds(), image.NewUniform(tt.fill), image.Point{}, draw.Src)
+
+			buf := new(bytes.Buffer)
+			if err := png.Encode(buf, src); err != nil {
+				t.Fatal(err)
+			}
+
+			img, err := Sniff(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			thumbnail, err := img.Thumbnail(20)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := jpeg.Decode(bytes.NewReader(thumbnail))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := decoded.Bounds().Size(); got != image.Pt(20, 30) {
+				t.Errorf("got size %v; want %v", got, image.Pt(20, 30))
+			}
+
+			got := color.GrayModel.Convert(decoded.At(10, 15)).(color.Gray)
+			if diff := int(got.Y) - int(tt.want.Y); diff < -3 || diff > 3 {
+				t.Errorf("got %v; want about %v", got, tt.want)
+			}
+		})
+	}
+}
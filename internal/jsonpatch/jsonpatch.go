@@ -0,0 +1,286 @@
+// Package jsonpatch applies RFC 6902 JSON Patch and RFC 7396 JSON Merge Patch
+// documents to JSON values.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTestFailed is returned when a test operation doesn't match the document.
+	ErrTestFailed = errors.New("test operation failed")
+)
+
+// Operation is a single RFC 6902 operation. Only add, remove, replace and test are
+// supported.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies the operations to doc in order and returns the patched document. The
+// patch is atomic: if any operation fails, an error is returned and doc is unchanged.
+func Apply(doc []byte, operations []Operation) ([]byte, error) {
+	var root any
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+
+	for i, operation := range operations {
+		tokens, err := parsePointer(operation.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		var value any
+		if operation.Op != "remove" {
+			if operation.Value == nil {
+				return nil, fmt.Errorf("operation %d: value must be provided", i)
+			}
+
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return nil, fmt.Errorf("operation %d: invalid value", i)
+			}
+		}
+
+		switch operation.Op {
+		case "add":
+			root, err = add(root, tokens, value)
+		case "remove":
+			root, err = remove(root, tokens)
+		case "replace":
+			if root, err = remove(root, tokens); err == nil {
+				root, err = add(root, tokens, value)
+			}
+		case "test":
+			var current any
+			if current, err = get(root, tokens); err == nil && !reflect.DeepEqual(current, value) {
+				err = ErrTestFailed
+			}
+		default:
+			err = fmt.Errorf("unsupported op %q", operation.Op)
+		}
+
+		if err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, err
+			}
+
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc: objects are merged recursively,
+// null members are removed and any other value replaces the target outright.
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	var target, changes any
+
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = merge(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, errors.New("path must not point at the whole document")
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+func get(node any, tokens []string) (any, error) {
+	for _, token := range tokens {
+		switch container := node.(type) {
+		case map[string]any:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q does not exist", token)
+			}
+			node = value
+
+		case []any:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+
+		default:
+			return nil, fmt.Errorf("path member %q does not exist", token)
+		}
+	}
+
+	return node, nil
+}
+
+// add inserts value at the location named by tokens within node and returns the
+// updated node. Arrays are rebuilt, so the caller must store the returned value.
+func add(node any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	token, rest := tokens[0], tokens[1:]
+
+	switch container := node.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			container[token] = value
+			return container, nil
+		}
+
+		child, ok := container[token]
+		if !ok {
+			return nil, fmt.Errorf("path member %q does not exist", token)
+		}
+
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		container[token] = child
+
+		return container, nil
+
+	case []any:
+		if len(rest) == 0 {
+			index := len(container)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(container)); err != nil {
+					return nil, err
+				}
+			}
+
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+
+			return container, nil
+		}
+
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := add(container[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = child
+
+		return container, nil
+	}
+
+	return nil, fmt.Errorf("path member %q does not exist", token)
+}
+
+// remove deletes the location named by tokens within node and returns the updated
+// node.
+func remove(node any, tokens []string) (any, error) {
+	token, rest := tokens[0], tokens[1:]
+
+	switch container := node.(type) {
+	case map[string]any:
+		child, ok := container[token]
+		if !ok {
+			return nil, fmt.Errorf("path member %q does not exist", token)
+		}
+
+		if len(rest) == 0 {
+			delete(container, token)
+			return container, nil
+		}
+
+		child, err := remove(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		container[token] = child
+
+		return container, nil
+
+	case []any:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rest) == 0 {
+			return append(container[:index:index], container[index+1:]...), nil
+		}
+
+		child, err := remove(container[index], rest)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = child
+
+		return container, nil
+	}
+
+	return nil, fmt.Errorf("path member %q does not exist", token)
+}
+
+// arrayIndex parses an array index token, which must be between 0 and last.
+func arrayIndex(token string, last int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	if index > last {
+		return 0, fmt.Errorf("array index %d is out of bounds", index)
+	}
+
+	return index, nil
+}
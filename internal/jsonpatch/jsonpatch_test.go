@@ -0,0 +1,243 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	const doc = `{"title":"Moana","year":2016,"genres":["animation","adventure"],"a/b":{"~c":1}}`
+
+	tests := []struct {
+		name       string
+		operations string
+		want       string
+		wantErr    bool
+		testFailed bool
+	}{
+		{
+			name:       "replace member",
+			operations: `[{"op":"replace","path":"/title","value":"Moana 2"}]`,
+			want:       `{"title":"Moana 2","year":2016,"genres":["animation","adventure"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:       "add member",
+			operations: `[{"op":"add","path":"/runtime","value":107}]`,
+			want:       `{"title":"Moana","year":2016,"runtime":107,"genres":["animation","adventure"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:       "remove member",
+			operations: `[{"op":"remove","path":"/year"}]`,
+			want:       `{"title":"Moana","genres":["animation","adventure"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:       "insert into array",
+			operations: `[{"op":"add","path":"/genres/1","value":"musical"}]`,
+			want:       `{"title":"Moana","year":2016,"genres":["animation","musical","adventure"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:       "append to array",
+			operations: `[{"op":"add","path":"/genres/-","value":"musical"}]`,
+			want:       `{"title":"Moana","year":2016,"genres":["animation","adventure","musical"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:       "remove from array",
+			operations: `[{"op":"remove","path":"/genres/0"}]`,
+			want:       `{"title":"Moana","year":2016,"genres":["adventure"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:       "escaped pointer",
+			operations: `[{"op":"replace","path":"/a~1b/~0c","value":2}]`,
+			want:       `{"title":"Moana","year":2016,"genres":["animation","adventure"],"a/b":{"~c":2}}`,
+		},
+		{
+			name:       "passing test then replace",
+			operations: `[{"op":"test","path":"/year","value":2016},{"op":"replace","path":"/year","value":2017}]`,
+			want:       `{"title":"Moana","year":2017,"genres":["animation","adventure"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:       "failing test",
+			operations: `[{"op":"replace","path":"/title","value":"Other"},{"op":"test","path":"/year","value":1999}]`,
+			wantErr:    true,
+			testFailed: true,
+		},
+		{
+			name:       "missing member",
+			operations: `[{"op":"replace","path":"/runtime","value":107}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "array index out of bounds",
+			operations: `[{"op":"add","path":"/genres/3","value":"musical"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "array index with leading zero",
+			operations: `[{"op":"remove","path":"/genres/01"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "whole document path",
+			operations: `[{"op":"replace","path":"","value":{}}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "path without leading slash",
+			operations: `[{"op":"remove","path":"title"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "missing value",
+			operations: `[{"op":"add","path":"/runtime"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "unsupported op",
+			operations: `[{"op":"move","path":"/title","value":"x"}]`,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var operations []Operation
+			if err := json.Unmarshal([]byte(tt.operations), &operations); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Apply([]byte(doc), operations)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s; want an error", got)
+				}
+				if errors.Is(err, ErrTestFailed) != tt.testFailed {
+					t.Errorf("got error %v; want ErrTestFailed %t", err, tt.testFailed)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+// Movie documents hold runtimes in their formatted form, so a test operation only
+// passes when the client sends the runtime exactly as the document has it.
+func TestApplyTestRuntime(t *testing.T) {
+	const doc = `{"title":"Moana","runtime":"107 mins"}`
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "same formatted value", value: `"107 mins"`},
+		{name: "bare minutes", value: `107`, wantErr: true},
+		{name: "other format", value: `"1h 47m"`, wantErr: true},
+		{name: "other runtime", value: `"108 mins"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations := []Operation{
+				{Op: "test", Path: "/runtime", Value: json.RawMessage(tt.value)},
+				{Op: "replace", Path: "/runtime", Value: json.RawMessage(`"110 mins"`)},
+			}
+
+			got, err := Apply([]byte(doc), operations)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTestFailed) {
+					t.Errorf("got %s, %v; want ErrTestFailed", got, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertJSONEqual(t, got, `{"title":"Moana","runtime":"110 mins"}`)
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "replace member",
+			doc:   `{"title":"Moana","year":2016}`,
+			patch: `{"title":"Moana 2"}`,
+			want:  `{"title":"Moana 2","year":2016}`,
+		},
+		{
+			name:  "null removes member",
+			doc:   `{"title":"Moana","year":2016}`,
+			patch: `{"year":null}`,
+			want:  `{"title":"Moana"}`,
+		},
+		{
+			name:  "nested objects merge",
+			doc:   `{"a":{"b":1,"c":2}}`,
+			patch: `{"a":{"c":3,"d":4}}`,
+			want:  `{"a":{"b":1,"c":3,"d":4}}`,
+		},
+		{
+			name:  "arrays are replaced",
+			doc:   `{"genres":["animation","adventure"]}`,
+			patch: `{"genres":["musical"]}`,
+			want:  `{"genres":["musical"]}`,
+		},
+		{
+			name:  "object replaces scalar",
+			doc:   `{"a":1}`,
+			patch: `{"a":{"b":null,"c":2}}`,
+			want:  `{"a":{"c":2}}`,
+		},
+		{
+			name:  "non-object patch replaces document",
+			doc:   `{"a":1}`,
+			patch: `[1,2]`,
+			want:  `[1,2]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var gotValue, wantValue any
+
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
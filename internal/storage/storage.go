@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Storage keeps uploaded files under slash-separated keys such as
+// "posters/abc123.jpg", and knows the URL each file is served from.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (File, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+	Key(url string) (string, bool)
+}
+
+// File is a stored file opened for serving.
+type File interface {
+	io.ReadSeekCloser
+	ModTime() time.Time
+}
+
+// Local stores files in a directory on the local filesystem. The files are expected
+// to be served by the API itself under baseURL.
+type Local struct {
+	root    string
+	baseURL string
+}
+
+func NewLocal(root string, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Local{root: root, baseURL: baseURL}, nil
+}
+
+// Put writes the file to a temporary name and renames it into place, so a file is
+// never served half written.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *Local) Open(ctx context.Context, key string) (File, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+
+	return &localFile{File: file, modTime: info.ModTime()}, nil
+}
+
+// Delete removes the file. Deleting a file that doesn't exist is not an error.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.baseURL + key
+}
+
+// Key is the inverse of URL. It reports false for URLs this storage doesn't serve.
+func (l *Local) Key(url string) (string, bool) {
+	return strings.CutPrefix(url, l.baseURL)
+}
+
+// path maps a key to a path under the root, rejecting keys that would escape it.
+func (l *Local) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+type localFile struct {
+	*os.File
+	modTime time.Time
+}
+
+func (f *localFile) ModTime() time.Time {
+	return f.modTime
+}
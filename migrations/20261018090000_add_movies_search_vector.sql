@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE movies
+  ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (setweight(to_tsvector('simple', title), 'A')) STORED;
+
+DROP INDEX IF EXISTS movies_title_idx;
+
+CREATE INDEX IF NOT EXISTS movies_title_idx ON movies USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS movies_title_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS search_vector;
+
+CREATE INDEX IF NOT EXISTS movies_title_idx ON movies USING GIN (to_tsvector('simple', title));
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS genres (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  name citext UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS genre_aliases (
+  alias citext PRIMARY KEY,
+  genre_id bigint NOT NULL REFERENCES genres ON DELETE CASCADE
+);
+
+-- Build the vocabulary from the genres already in use. Names differing only by case
+-- collapse into one genre, and movies are rewritten to use its spelling.
+INSERT INTO genres (name)
+SELECT DISTINCT unnest(genres) FROM movies ORDER BY 1
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE movies
+SET genres = ARRAY(
+  SELECT genres.name::text
+  FROM unnest(movies.genres) WITH ORDINALITY AS t(genre, position)
+  INNER JOIN genres ON genres.name = t.genre::citext
+  GROUP BY genres.name
+  ORDER BY min(t.position)
+)
+WHERE NOT movies.genres <@ (SELECT array_agg(name::text) FROM genres);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS genre_aliases;
+
+DROP TABLE IF EXISTS genres;
+-- +goose StatementEnd
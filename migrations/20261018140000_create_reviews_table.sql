@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reviews (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  rating integer NOT NULL CHECK (rating BETWEEN 1 AND 10),
+  body text NOT NULL DEFAULT '',
+  version integer NOT NULL DEFAULT 1,
+  UNIQUE (movie_id, user_id)
+);
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS rating_average real NOT NULL DEFAULT 0;
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS rating_count integer NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS movies_rating_average_idx ON movies (rating_average);
+
+INSERT INTO permissions (code)
+VALUES
+  ('reviews:write');
+
+-- New users are given reviews:write when they register; existing users get it here.
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT users.id, permissions.id
+FROM users, permissions
+WHERE users.activated AND permissions.code = 'reviews:write'
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE code = 'reviews:write');
+
+DELETE FROM permissions WHERE code = 'reviews:write';
+
+DROP INDEX IF EXISTS movies_rating_average_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS rating_count;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS rating_average;
+
+DROP TABLE IF EXISTS reviews;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS watchlist_items (
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  position integer NOT NULL CHECK (position > 0),
+  added_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS watchlist_items_user_position_idx ON watchlist_items (user_id, position);
+
+CREATE TABLE IF NOT EXISTS diary_entries (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  watched_on date NOT NULL,
+  rating integer CHECK (rating BETWEEN 1 AND 10),
+  notes text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS diary_entries_user_watched_on_idx ON diary_entries (user_id, watched_on);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS diary_entries;
+
+DROP TABLE IF EXISTS watchlist_items;
+-- +goose StatementEnd
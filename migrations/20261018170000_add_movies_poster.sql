@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS poster_url text NOT NULL DEFAULT '';
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS poster_thumbnail_url text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE movies DROP COLUMN IF EXISTS poster_thumbnail_url;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS poster_url;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS movie_titles (
+  id bigserial PRIMARY KEY,
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  kind text NOT NULL CHECK (kind IN ('translation', 'alternative')),
+  locale text NOT NULL DEFAULT '',
+  title text NOT NULL,
+  search_vector tsvector GENERATED ALWAYS AS (to_tsvector('simple', title)) STORED,
+  CHECK (kind = 'alternative' OR locale <> '')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS movie_titles_translation_idx ON movie_titles (movie_id, locale) WHERE kind = 'translation';
+
+CREATE INDEX IF NOT EXISTS movie_titles_movie_id_idx ON movie_titles (movie_id);
+
+CREATE INDEX IF NOT EXISTS movie_titles_search_idx ON movie_titles USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS movie_titles_title_trgm_idx ON movie_titles USING GIN (title gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS movie_titles;
+-- +goose StatementEnd
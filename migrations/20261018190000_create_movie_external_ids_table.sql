@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS movie_external_ids (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  source text NOT NULL,
+  external_id text NOT NULL,
+  PRIMARY KEY (movie_id, source),
+  UNIQUE (source, external_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS movie_external_ids;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Duplicate detection only compares a new movie with the movies from its year.
+CREATE INDEX IF NOT EXISTS movies_year_idx ON movies (year);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS movies_year_idx;
+-- +goose StatementEnd
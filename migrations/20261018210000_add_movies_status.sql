@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published'
+  CHECK (status IN ('draft', 'in_review', 'published', 'archived'));
+
+-- Movies that already exist stay visible; new ones start out as drafts.
+ALTER TABLE movies ALTER COLUMN status SET DEFAULT 'draft';
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS movies_status_idx ON movies (status);
+
+CREATE INDEX IF NOT EXISTS movies_publish_at_idx ON movies (publish_at) WHERE status = 'in_review';
+
+INSERT INTO permissions (code)
+VALUES
+  ('movies:publish');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE code = 'movies:publish';
+
+DROP INDEX IF EXISTS movies_publish_at_idx;
+
+DROP INDEX IF EXISTS movies_status_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS publish_at;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd